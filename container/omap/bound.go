@@ -0,0 +1,80 @@
+package omap
+
+// Floor 返回小于等于key的最大元素，不存在时found为false。
+func (m *OrderedMap[K, V]) Floor(key K) (k K, value V, found bool) {
+	return pair(m.floor(key, true))
+}
+
+// Ceiling 返回大于等于key的最小元素，不存在时found为false。
+func (m *OrderedMap[K, V]) Ceiling(key K) (k K, value V, found bool) {
+	return pair(m.ceiling(key, true))
+}
+
+// Lower 返回严格小于key的最大元素（前驱），不存在时found为false。
+func (m *OrderedMap[K, V]) Lower(key K) (k K, value V, found bool) {
+	return pair(m.floor(key, false))
+}
+
+// Higher 返回严格大于key的最小元素（后继），不存在时found为false。
+func (m *OrderedMap[K, V]) Higher(key K) (k K, value V, found bool) {
+	return pair(m.ceiling(key, false))
+}
+
+// PopFirst 删除并返回key最小的元素，map为空时found为false。
+// 配合Insert，可以把map当作按key排序的优先队列使用。
+func (m *OrderedMap[K, V]) PopFirst() (key K, value V, found bool) {
+	if key, value, found = m.First(); found {
+		m.Delete(key)
+	}
+	return key, value, found
+}
+
+// PopLast 删除并返回key最大的元素，map为空时found为false。
+func (m *OrderedMap[K, V]) PopLast() (key K, value V, found bool) {
+	if key, value, found = m.Latest(); found {
+		m.Delete(key)
+	}
+	return key, value, found
+}
+
+// floor 返回小于key的最大节点，orEqual为true时包含等于key的节点。
+func (m *OrderedMap[K, V]) floor(key K, orEqual bool) (res *node[K, V]) {
+	for root := m.root; root != nil; {
+		c := m.compare(key, root.key)
+		if c > 0 || c == 0 && orEqual {
+			res = root
+			if c == 0 {
+				break
+			}
+			root = root.right
+		} else {
+			root = root.left
+		}
+	}
+	return res
+}
+
+// ceiling 返回大于key的最小节点，orEqual为true时包含等于key的节点。
+func (m *OrderedMap[K, V]) ceiling(key K, orEqual bool) (res *node[K, V]) {
+	for root := m.root; root != nil; {
+		c := m.compare(key, root.key)
+		if c < 0 || c == 0 && orEqual {
+			res = root
+			if c == 0 {
+				break
+			}
+			root = root.left
+		} else {
+			root = root.right
+		}
+	}
+	return res
+}
+
+// pair 返回节点的key与value，节点为nil时返回零值与false。
+func pair[K, V any](n *node[K, V]) (key K, value V, found bool) {
+	if n == nil {
+		return key, value, false
+	}
+	return n.key, n.value, true
+}
@@ -0,0 +1,86 @@
+package omap_test
+
+import (
+	"testing"
+
+	"github.com/alex023/basekit/container/omap"
+)
+
+func TestFloorCeiling(t *testing.T) {
+	m := evenMap(10) // 0, 2, ..., 18
+	type lookup func(int) (int, int, bool)
+	tests := []struct {
+		name string
+		fn   lookup
+		key  int
+		want int // -1表示不存在
+	}{
+		{"Floor", m.Floor, 5, 4},
+		{"Floor", m.Floor, 6, 6},
+		{"Floor", m.Floor, -1, -1},
+		{"Floor", m.Floor, 100, 18},
+		{"Ceiling", m.Ceiling, 5, 6},
+		{"Ceiling", m.Ceiling, 6, 6},
+		{"Ceiling", m.Ceiling, 19, -1},
+		{"Ceiling", m.Ceiling, -100, 0},
+		{"Lower", m.Lower, 6, 4},
+		{"Lower", m.Lower, 7, 6},
+		{"Lower", m.Lower, 0, -1},
+		{"Higher", m.Higher, 6, 8},
+		{"Higher", m.Higher, 5, 6},
+		{"Higher", m.Higher, 18, -1},
+	}
+	for _, tt := range tests {
+		key, value, ok := tt.fn(tt.key)
+		if ok != (tt.want >= 0) || ok && (key != tt.want || value != tt.want/2) {
+			t.Errorf("%s(%d) = %d, %d, %v, should be %d", tt.name, tt.key, key, value, ok, tt.want)
+		}
+	}
+}
+
+func TestPop(t *testing.T) {
+	m := evenMap(5) // 0, 2, 4, 6, 8
+	if k, v, ok := m.PopFirst(); !ok || k != 0 || v != 0 {
+		t.Errorf("PopFirst() = %d, %d, %v", k, v, ok)
+	}
+	if k, v, ok := m.PopLast(); !ok || k != 8 || v != 4 {
+		t.Errorf("PopLast() = %d, %d, %v", k, v, ok)
+	}
+	if m.Len() != 3 {
+		t.Errorf("map len %d should be 3", m.Len())
+	}
+	for want := 2; want <= 6; want += 2 {
+		if k, _, ok := m.PopFirst(); !ok || k != want {
+			t.Errorf("PopFirst() = %d, %v, should be %d", k, ok, want)
+		}
+	}
+	if _, _, ok := m.PopFirst(); ok {
+		t.Error("PopFirst() on empty map should fail")
+	}
+	if _, _, ok := m.PopLast(); ok {
+		t.Error("PopLast() on empty map should fail")
+	}
+}
+
+func TestSchedulerQueue(t *testing.T) {
+	// 按到期时间排序的任务队列
+	type task struct {
+		due  int
+		name string
+	}
+	q := omap.NewOrdered[int, string]()
+	for _, tk := range []task{{30, "c"}, {10, "a"}, {20, "b"}} {
+		q.Insert(tk.due, tk.name)
+	}
+	var order string
+	for {
+		_, name, ok := q.PopFirst()
+		if !ok {
+			break
+		}
+		order += name
+	}
+	if order != "abc" {
+		t.Errorf("order %q should be %q", order, "abc")
+	}
+}
@@ -0,0 +1,477 @@
+package omap
+
+import (
+	"cmp"
+	"iter"
+	"slices"
+)
+
+// DefaultDegree 是NewBTree的degree小于2时使用的最小度数。
+const DefaultDegree = 32
+
+// SortedMap 是OrderedMap与BTree共同实现的有序map接口，便于在两种实现之间切换。
+type SortedMap[K, V any] interface {
+	Insert(key K, value V) (inserted bool)
+	Find(key K) (value V, found bool)
+	Delete(key K) (deleted bool)
+	First() (key K, value V, found bool)
+	Latest() (key K, value V, found bool)
+	Floor(key K) (k K, value V, found bool)
+	Ceiling(key K) (k K, value V, found bool)
+	Lower(key K) (k K, value V, found bool)
+	Higher(key K) (k K, value V, found bool)
+	PopFirst() (key K, value V, found bool)
+	PopLast() (key K, value V, found bool)
+	Len() int
+	Clear()
+	Do(function func(K, V))
+	Range(lo, hi K, fn func(key K, value V) bool, opts ...RangeOption)
+	All() iter.Seq2[K, V]
+	Backward() iter.Seq2[K, V]
+	From(key K) iter.Seq2[K, V]
+}
+
+var (
+	_ SortedMap[int, int] = (*OrderedMap[int, int])(nil)
+	_ SortedMap[int, int] = (*BTree[int, int])(nil)
+)
+
+// BTree 是基于B树的有序map，方法与OrderedMap相同（见SortedMap）。
+//
+// 每个节点在连续的切片中保存多个元素，相比每个元素一个节点的红黑树，
+// 指针更少、访问内存更连续，适合数百万元素的大map。
+// The zero value is an invalid map! 通过NewBTree或NewBTreeFunc创建。
+type BTree[K, V any] struct {
+	root    *bnode[K, V]
+	compare func(a, b K) int
+	degree  int
+	length  int
+}
+
+// bnode 是B树的节点。叶子节点没有children，否则len(children) == len(items)+1，
+// children[i]中的key位于items[i-1]与items[i]之间。
+type bnode[K, V any] struct {
+	items    []Entry[K, V]
+	children []*bnode[K, V]
+}
+
+// NewBTree 返回一个空的BTree,其key按照cmp.Compare的顺序排列。
+// degree为B树的最小度数t：除根节点外，每个节点保存t-1到2t-1个元素；小于2时使用DefaultDegree。
+func NewBTree[K cmp.Ordered, V any](degree int) *BTree[K, V] {
+	return NewBTreeFunc[K, V](degree, cmp.Compare[K])
+}
+
+// NewBTreeFunc 返回一个空的BTree,key通过compare比较，含义与NewOrderedFunc相同。
+func NewBTreeFunc[K, V any](degree int, compare func(a, b K) int) *BTree[K, V] {
+	if degree < 2 {
+		degree = DefaultDegree
+	}
+	return &BTree[K, V]{compare: compare, degree: degree}
+}
+
+// maxItems 返回节点最多保存的元素数量。
+func (t *BTree[K, V]) maxItems() int {
+	return 2*t.degree - 1
+}
+
+// minItems 返回除根节点外，节点最少保存的元素数量。
+func (t *BTree[K, V]) minItems() int {
+	return t.degree - 1
+}
+
+// find 在n中二分查找key，返回key所在或者应当插入的位置。
+func (t *BTree[K, V]) find(n *bnode[K, V], key K) (int, bool) {
+	return slices.BinarySearchFunc(n.items, key, func(e Entry[K, V], key K) int {
+		return t.compare(e.Key, key)
+	})
+}
+
+// Insert 插入新的元素并返回true；key已经存在时替换它的value并返回false。
+func (t *BTree[K, V]) Insert(key K, value V) (inserted bool) {
+	e := Entry[K, V]{key, value}
+	if t.root == nil {
+		t.root = &bnode[K, V]{items: []Entry[K, V]{e}}
+		t.length++
+		return true
+	}
+	if len(t.root.items) >= t.maxItems() {
+		mid, second := t.split(t.root, t.maxItems()/2)
+		t.root = &bnode[K, V]{items: []Entry[K, V]{mid}, children: []*bnode[K, V]{t.root, second}}
+	}
+	if inserted = t.insert(t.root, e); inserted {
+		t.length++
+	}
+	return inserted
+}
+
+// insert 把e插入到不满的节点n为根的子树中。下行时预先分裂满的子节点，因此不需要回溯。
+func (t *BTree[K, V]) insert(n *bnode[K, V], e Entry[K, V]) bool {
+	for {
+		i, found := t.find(n, e.Key)
+		if found {
+			n.items[i].Value = e.Value
+			return false
+		}
+		if len(n.children) == 0 {
+			n.items = slices.Insert(n.items, i, e)
+			return true
+		}
+		if len(n.children[i].items) >= t.maxItems() {
+			mid, second := t.split(n.children[i], t.maxItems()/2)
+			n.items = slices.Insert(n.items, i, mid)
+			n.children = slices.Insert(n.children, i+1, second)
+			if c := t.compare(e.Key, mid.Key); c == 0 {
+				n.items[i].Value = e.Value
+				return false
+			} else if c > 0 {
+				i++
+			}
+		}
+		n = n.children[i]
+	}
+}
+
+// split 在位置i处分裂n：n保留i之前的元素，返回位置i的元素以及保存其后元素的新节点。
+func (t *BTree[K, V]) split(n *bnode[K, V], i int) (Entry[K, V], *bnode[K, V]) {
+	mid := n.items[i]
+	next := &bnode[K, V]{items: make([]Entry[K, V], 0, t.maxItems())}
+	next.items = append(next.items, n.items[i+1:]...)
+	n.items = truncate(n.items, i)
+	if len(n.children) > 0 {
+		next.children = make([]*bnode[K, V], 0, t.maxItems()+1)
+		next.children = append(next.children, n.children[i+1:]...)
+		n.children = truncate(n.children, i+1)
+	}
+	return mid, next
+}
+
+// truncate 把s截断为n个元素，并清空被截掉的部分，避免其中的引用阻止垃圾回收。
+func truncate[S ~[]E, E any](s S, n int) S {
+	clear(s[n:])
+	return s[:n]
+}
+
+// Find 返回key对应的value。
+func (t *BTree[K, V]) Find(key K) (value V, found bool) {
+	for n := t.root; n != nil; {
+		i, found := t.find(n, key)
+		if found {
+			return n.items[i].Value, true
+		}
+		if len(n.children) == 0 {
+			break
+		}
+		n = n.children[i]
+	}
+	return value, false
+}
+
+// removeType 指定remove删除的元素。
+type removeType int
+
+const (
+	removeKey removeType = iota // 删除指定的key
+	removeMin                   // 删除最小的元素
+	removeMax                   // 删除最大的元素
+)
+
+// Delete 删除key对应的元素并返回true；key不存在时返回false。
+func (t *BTree[K, V]) Delete(key K) (deleted bool) {
+	_, deleted = t.delete(key, removeKey)
+	return deleted
+}
+
+// PopFirst 删除并返回key最小的元素，map为空时found为false。
+func (t *BTree[K, V]) PopFirst() (key K, value V, found bool) {
+	e, found := t.delete(key, removeMin)
+	return e.Key, e.Value, found
+}
+
+// PopLast 删除并返回key最大的元素，map为空时found为false。
+func (t *BTree[K, V]) PopLast() (key K, value V, found bool) {
+	e, found := t.delete(key, removeMax)
+	return e.Key, e.Value, found
+}
+
+// delete 删除一个元素，并在根节点为空时降低树的高度。
+func (t *BTree[K, V]) delete(key K, typ removeType) (e Entry[K, V], found bool) {
+	if t.root == nil {
+		return e, false
+	}
+	e, found = t.remove(t.root, key, typ)
+	if len(t.root.items) == 0 {
+		if len(t.root.children) > 0 {
+			t.root = t.root.children[0]
+		} else {
+			t.root = nil
+		}
+	}
+	if found {
+		t.length--
+	}
+	return e, found
+}
+
+// remove 从n为根的子树中删除一个元素。下行之前保证子节点的元素多于minItems，
+// 因此删除之后不需要回溯调整。
+func (t *BTree[K, V]) remove(n *bnode[K, V], key K, typ removeType) (e Entry[K, V], found bool) {
+	var i int
+	switch typ {
+	case removeMin:
+		if len(n.children) == 0 {
+			e = n.items[0]
+			n.items = slices.Delete(n.items, 0, 1)
+			return e, true
+		}
+	case removeMax:
+		if len(n.children) == 0 {
+			e = n.items[len(n.items)-1]
+			n.items = truncate(n.items, len(n.items)-1)
+			return e, true
+		}
+		i = len(n.items)
+	default:
+		i, found = t.find(n, key)
+		if len(n.children) == 0 {
+			if found {
+				e = n.items[i]
+				n.items = slices.Delete(n.items, i, i+1)
+			}
+			return e, found
+		}
+	}
+	if len(n.children[i].items) <= t.minItems() {
+		t.grow(n, i)
+		// 调整之后key可能移动到了别的位置，重新查找
+		return t.remove(n, key, typ)
+	}
+	if found {
+		// 用左子树中最大的元素替换被删除的元素
+		e = n.items[i]
+		n.items[i], _ = t.remove(n.children[i], key, removeMax)
+		return e, true
+	}
+	return t.remove(n.children[i], key, typ)
+}
+
+// grow 使n的第i个子节点的元素多于minItems：从相邻的兄弟节点借一个元素，
+// 或者与兄弟节点合并。
+func (t *BTree[K, V]) grow(n *bnode[K, V], i int) {
+	child := n.children[i]
+	switch {
+	case i > 0 && len(n.children[i-1].items) > t.minItems():
+		left := n.children[i-1]
+		child.items = slices.Insert(child.items, 0, n.items[i-1])
+		n.items[i-1] = left.items[len(left.items)-1]
+		left.items = truncate(left.items, len(left.items)-1)
+		if len(left.children) > 0 {
+			child.children = slices.Insert(child.children, 0, left.children[len(left.children)-1])
+			left.children = truncate(left.children, len(left.children)-1)
+		}
+	case i < len(n.items) && len(n.children[i+1].items) > t.minItems():
+		right := n.children[i+1]
+		child.items = append(child.items, n.items[i])
+		n.items[i] = right.items[0]
+		right.items = slices.Delete(right.items, 0, 1)
+		if len(right.children) > 0 {
+			child.children = append(child.children, right.children[0])
+			right.children = slices.Delete(right.children, 0, 1)
+		}
+	default:
+		if i >= len(n.items) {
+			i--
+			child = n.children[i]
+		}
+		right := n.children[i+1]
+		child.items = append(child.items, n.items[i])
+		child.items = append(child.items, right.items...)
+		child.children = append(child.children, right.children...)
+		n.items = slices.Delete(n.items, i, i+1)
+		n.children = slices.Delete(n.children, i+1, i+2)
+	}
+}
+
+// First 返回key最小的元素。
+func (t *BTree[K, V]) First() (key K, value V, found bool) {
+	n := t.root
+	if n == nil {
+		return key, value, false
+	}
+	for len(n.children) > 0 {
+		n = n.children[0]
+	}
+	return n.items[0].Key, n.items[0].Value, true
+}
+
+// Latest 返回key最大的元素。
+func (t *BTree[K, V]) Latest() (key K, value V, found bool) {
+	n := t.root
+	if n == nil {
+		return key, value, false
+	}
+	for len(n.children) > 0 {
+		n = n.children[len(n.children)-1]
+	}
+	e := n.items[len(n.items)-1]
+	return e.Key, e.Value, true
+}
+
+// Floor 返回小于等于key的最大元素，不存在时found为false。
+func (t *BTree[K, V]) Floor(key K) (k K, value V, found bool) {
+	return t.nearest(key, true, true)
+}
+
+// Ceiling 返回大于等于key的最小元素，不存在时found为false。
+func (t *BTree[K, V]) Ceiling(key K) (k K, value V, found bool) {
+	return t.nearest(key, false, true)
+}
+
+// Lower 返回严格小于key的最大元素，不存在时found为false。
+func (t *BTree[K, V]) Lower(key K) (k K, value V, found bool) {
+	return t.nearest(key, true, false)
+}
+
+// Higher 返回严格大于key的最小元素，不存在时found为false。
+func (t *BTree[K, V]) Higher(key K) (k K, value V, found bool) {
+	return t.nearest(key, false, false)
+}
+
+// nearest 返回遍历时遇到的第一个元素：below为true时从key开始降序遍历，否则升序遍历。
+func (t *BTree[K, V]) nearest(key K, below, inclusive bool) (k K, value V, found bool) {
+	visit := func(e *Entry[K, V]) bool {
+		k, value, found = e.Key, e.Value, true
+		return false
+	}
+	if below {
+		t.descend(t.root, &key, inclusive, visit)
+	} else {
+		t.ascend(t.root, &key, inclusive, visit)
+	}
+	return k, value, found
+}
+
+// Len 返回map中的键值对数量。
+func (t *BTree[K, V]) Len() int {
+	return t.length
+}
+
+// Clear 删除所有元素。
+func (t *BTree[K, V]) Clear() {
+	t.root = nil
+	t.length = 0
+}
+
+// Do 按key升序对每个元素调用function。
+func (t *BTree[K, V]) Do(function func(K, V)) {
+	t.ascend(t.root, nil, false, func(e *Entry[K, V]) bool {
+		function(e.Key, e.Value)
+		return true
+	})
+}
+
+// Range 按顺序对区间内的每个元素调用fn，fn返回false时停止遍历，区间的含义与OrderedMap.Range相同。
+func (t *BTree[K, V]) Range(lo, hi K, fn func(key K, value V) bool, opts ...RangeOption) {
+	opt := combine(opts)
+	if opt&Reverse == 0 {
+		t.ascend(t.root, &lo, opt&LoExclusive == 0, func(e *Entry[K, V]) bool {
+			c := t.compare(e.Key, hi)
+			return (c < 0 || c == 0 && opt&HiInclusive != 0) && fn(e.Key, e.Value)
+		})
+		return
+	}
+	t.descend(t.root, &hi, opt&HiInclusive != 0, func(e *Entry[K, V]) bool {
+		c := t.compare(e.Key, lo)
+		return (c > 0 || c == 0 && opt&LoExclusive == 0) && fn(e.Key, e.Value)
+	})
+}
+
+// All 返回按key升序遍历所有元素的迭代器。
+func (t *BTree[K, V]) All() iter.Seq2[K, V] {
+	return func(yield func(K, V) bool) {
+		t.ascend(t.root, nil, false, func(e *Entry[K, V]) bool {
+			return yield(e.Key, e.Value)
+		})
+	}
+}
+
+// Backward 返回按key降序遍历所有元素的迭代器。
+func (t *BTree[K, V]) Backward() iter.Seq2[K, V] {
+	return func(yield func(K, V) bool) {
+		t.descend(t.root, nil, false, func(e *Entry[K, V]) bool {
+			return yield(e.Key, e.Value)
+		})
+	}
+}
+
+// From 返回从大于等于key的最小元素开始，按key升序遍历的迭代器。
+func (t *BTree[K, V]) From(key K) iter.Seq2[K, V] {
+	return func(yield func(K, V) bool) {
+		t.ascend(t.root, &key, true, func(e *Entry[K, V]) bool {
+			return yield(e.Key, e.Value)
+		})
+	}
+}
+
+// ascend 按升序对n为根的子树中不小于lo的元素调用fn（inclusive为false时跳过等于lo的元素），
+// lo为nil表示没有下界。fn返回false时停止遍历，并返回false。
+func (t *BTree[K, V]) ascend(n *bnode[K, V], lo *K, inclusive bool, fn func(*Entry[K, V]) bool) bool {
+	if n == nil {
+		return true
+	}
+	i := 0
+	if lo != nil {
+		var found bool
+		if i, found = t.find(n, *lo); found {
+			// children[i]中的元素都小于lo，之后的子树中的元素都大于lo
+			if inclusive && !fn(&n.items[i]) {
+				return false
+			}
+			i++
+			lo = nil
+		}
+	}
+	for ; i < len(n.items); i++ {
+		if len(n.children) > 0 && !t.ascend(n.children[i], lo, inclusive, fn) {
+			return false
+		}
+		lo = nil // 之后的子树中的元素都大于lo
+		if !fn(&n.items[i]) {
+			return false
+		}
+	}
+	if len(n.children) > 0 {
+		return t.ascend(n.children[len(n.items)], lo, inclusive, fn)
+	}
+	return true
+}
+
+// descend 按降序对n为根的子树中不大于hi的元素调用fn（inclusive为false时跳过等于hi的元素），
+// hi为nil表示没有上界。fn返回false时停止遍历，并返回false。
+func (t *BTree[K, V]) descend(n *bnode[K, V], hi *K, inclusive bool, fn func(*Entry[K, V]) bool) bool {
+	if n == nil {
+		return true
+	}
+	i := len(n.items)
+	if hi != nil {
+		var found bool
+		if i, found = t.find(n, *hi); found {
+			// children[i+1]中的元素都大于hi，children[i]及之前的子树中的元素都小于hi
+			if inclusive && !fn(&n.items[i]) {
+				return false
+			}
+			hi = nil
+		}
+	}
+	if len(n.children) > 0 && !t.descend(n.children[i], hi, inclusive, fn) {
+		return false
+	}
+	for i--; i >= 0; i-- {
+		if !fn(&n.items[i]) {
+			return false
+		}
+		if len(n.children) > 0 && !t.descend(n.children[i], nil, false, fn) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,236 @@
+package omap_test
+
+import (
+	"math/rand"
+	"slices"
+	"strconv"
+	"testing"
+
+	"github.com/alex023/basekit/container/omap"
+)
+
+// collect 返回迭代器产生的所有key。
+func collect(seq func(func(int, int) bool)) []int {
+	var keys []int
+	seq(func(k, _ int) bool {
+		keys = append(keys, k)
+		return true
+	})
+	return keys
+}
+
+// 对BTree与OrderedMap执行相同的随机操作，比较两者的结果
+func TestBTreeRandom(t *testing.T) {
+	for _, degree := range []int{2, 3, 4, 32} {
+		t.Run(strconv.Itoa(degree), func(t *testing.T) {
+			r := rand.New(rand.NewSource(int64(degree)))
+			want := omap.NewOrdered[int, int]()
+			var got omap.SortedMap[int, int] = omap.NewBTree[int, int](degree)
+			for i := 0; i < 20000; i++ {
+				key := r.Intn(2000)
+				switch op := r.Intn(10); {
+				case op < 5:
+					if a, b := got.Insert(key, i), want.Insert(key, i); a != b {
+						t.Fatalf("Insert(%d) = %v, should be %v", key, a, b)
+					}
+				case op < 8:
+					if a, b := got.Delete(key), want.Delete(key); a != b {
+						t.Fatalf("Delete(%d) = %v, should be %v", key, a, b)
+					}
+				case op < 9:
+					k1, v1, ok1 := got.PopFirst()
+					k2, v2, ok2 := want.PopFirst()
+					if k1 != k2 || v1 != v2 || ok1 != ok2 {
+						t.Fatalf("PopFirst() = %d, %d, %v, should be %d, %d, %v", k1, v1, ok1, k2, v2, ok2)
+					}
+				default:
+					k1, v1, ok1 := got.PopLast()
+					k2, v2, ok2 := want.PopLast()
+					if k1 != k2 || v1 != v2 || ok1 != ok2 {
+						t.Fatalf("PopLast() = %d, %d, %v, should be %d, %d, %v", k1, v1, ok1, k2, v2, ok2)
+					}
+				}
+				if got.Len() != want.Len() {
+					t.Fatalf("map len %d should be %d", got.Len(), want.Len())
+				}
+			}
+			if a, b := collect(got.All()), collect(want.All()); !slices.Equal(a, b) {
+				t.Fatalf("All() = %v, should be %v", a, b)
+			}
+			if a, b := collect(got.Backward()), collect(want.Backward()); !slices.Equal(a, b) {
+				t.Fatalf("Backward() = %v, should be %v", a, b)
+			}
+			for key := -1; key <= 2000; key++ {
+				v1, ok1 := got.Find(key)
+				v2, ok2 := want.Find(key)
+				if v1 != v2 || ok1 != ok2 {
+					t.Fatalf("Find(%d) = %d, %v, should be %d, %v", key, v1, ok1, v2, ok2)
+				}
+			}
+		})
+	}
+}
+
+func TestBTreeBounds(t *testing.T) {
+	want := evenMap(500)
+	got := omap.NewBTree[int, int](2)
+	want.Do(func(k, v int) { got.Insert(k, v) })
+	type lookup func(int) (int, int, bool)
+	pairs := []struct {
+		name      string
+		got, want lookup
+	}{
+		{"Floor", got.Floor, want.Floor},
+		{"Ceiling", got.Ceiling, want.Ceiling},
+		{"Lower", got.Lower, want.Lower},
+		{"Higher", got.Higher, want.Higher},
+	}
+	for _, p := range pairs {
+		for key := -2; key <= 1001; key++ {
+			k1, v1, ok1 := p.got(key)
+			k2, v2, ok2 := p.want(key)
+			if k1 != k2 || v1 != v2 || ok1 != ok2 {
+				t.Fatalf("%s(%d) = %d, %d, %v, should be %d, %d, %v", p.name, key, k1, v1, ok1, k2, v2, ok2)
+			}
+		}
+	}
+	if k, _, _ := got.First(); k != 0 {
+		t.Errorf("First() = %d, should be 0", k)
+	}
+	if k, _, _ := got.Latest(); k != 998 {
+		t.Errorf("Latest() = %d, should be 998", k)
+	}
+	if a, b := collect(got.From(501)), collect(want.From(501)); !slices.Equal(a, b) {
+		t.Errorf("From(501) = %v, should be %v", a, b)
+	}
+}
+
+func TestBTreeRange(t *testing.T) {
+	want := evenMap(300)
+	got := omap.NewBTree[int, int](3)
+	want.Do(func(k, v int) { got.Insert(k, v) })
+	options := [][]omap.RangeOption{
+		nil,
+		{omap.LoExclusive},
+		{omap.HiInclusive},
+		{omap.LoExclusive, omap.HiInclusive},
+		{omap.Reverse},
+		{omap.Reverse, omap.LoExclusive},
+		{omap.Reverse, omap.HiInclusive},
+		{omap.Reverse, omap.LoExclusive, omap.HiInclusive},
+	}
+	for _, opts := range options {
+		for lo := -1; lo < 600; lo += 7 {
+			for _, hi := range []int{lo, lo + 1, lo + 2, lo + 50, 601} {
+				a := rangeKeys(got, lo, hi, opts...)
+				b := rangeKeys(want, lo, hi, opts...)
+				if !slices.Equal(a, b) {
+					t.Fatalf("Range(%d, %d, %v) = %v, should be %v", lo, hi, opts, a, b)
+				}
+			}
+		}
+	}
+	// fn返回false时停止遍历
+	n := 0
+	got.Range(0, 600, func(int, int) bool {
+		n++
+		return n < 3
+	})
+	if n != 3 {
+		t.Errorf("Range should stop after 3 calls, got %d", n)
+	}
+}
+
+func TestBTreeEmpty(t *testing.T) {
+	m := omap.NewBTreeFunc[string, int](0, func(a, b string) int { return len(a) - len(b) })
+	if _, ok := m.Find("a"); ok {
+		t.Error("Find on empty map should fail")
+	}
+	if m.Delete("a") {
+		t.Error("Delete on empty map should fail")
+	}
+	if _, _, ok := m.First(); ok {
+		t.Error("First on empty map should fail")
+	}
+	if _, _, ok := m.PopLast(); ok {
+		t.Error("PopLast on empty map should fail")
+	}
+	// 按长度比较，长度相同的key视为同一个key
+	m.Insert("ab", 1)
+	if m.Insert("cd", 2) {
+		t.Error("Insert with an equal key should replace the value")
+	}
+	if v, _ := m.Find("xy"); v != 2 || m.Len() != 1 {
+		t.Errorf("Find(xy) = %d, len %d", v, m.Len())
+	}
+	m.Clear()
+	if _, _, ok := m.Latest(); ok || m.Len() != 0 {
+		t.Error("Clear should remove all entries")
+	}
+}
+
+const benchSize = 100000
+
+// benchMaps 返回用于比较的两种实现。
+func benchMaps() []struct {
+	name string
+	new  func() omap.SortedMap[int, int]
+} {
+	return []struct {
+		name string
+		new  func() omap.SortedMap[int, int]
+	}{
+		{"RedBlack", func() omap.SortedMap[int, int] { return omap.NewOrdered[int, int]() }},
+		{"BTree", func() omap.SortedMap[int, int] { return omap.NewBTree[int, int](omap.DefaultDegree) }},
+	}
+}
+
+func BenchmarkSortedMapInsert(b *testing.B) {
+	keys := rand.New(rand.NewSource(1)).Perm(benchSize)
+	for _, bm := range benchMaps() {
+		b.Run(bm.name, func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				m := bm.new()
+				for _, k := range keys {
+					m.Insert(k, k)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkSortedMapFind(b *testing.B) {
+	keys := rand.New(rand.NewSource(1)).Perm(benchSize)
+	for _, bm := range benchMaps() {
+		b.Run(bm.name, func(b *testing.B) {
+			m := bm.new()
+			for _, k := range keys {
+				m.Insert(k, k)
+			}
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				m.Find(keys[i%len(keys)])
+			}
+		})
+	}
+}
+
+func BenchmarkSortedMapScan(b *testing.B) {
+	keys := rand.New(rand.NewSource(1)).Perm(benchSize)
+	for _, bm := range benchMaps() {
+		b.Run(bm.name, func(b *testing.B) {
+			m := bm.new()
+			for _, k := range keys {
+				m.Insert(k, k)
+			}
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				sum := 0
+				for _, v := range m.All() {
+					sum += v
+				}
+			}
+		})
+	}
+}
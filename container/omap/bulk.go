@@ -0,0 +1,201 @@
+package omap
+
+import (
+	"cmp"
+	"errors"
+	"math/bits"
+)
+
+// ErrNotSorted 表示FromSorted的输入没有按key严格升序排列。
+var ErrNotSorted = errors.New("omap: keys are not strictly increasing")
+
+// Entry 是一个键值对。
+type Entry[K, V any] struct {
+	Key   K
+	Value V
+}
+
+// FromSorted 由按key严格升序排列的entries构造OrderedMap，复杂度为O(n)，
+// 而逐个Insert需要O(n log n)。key没有严格升序排列时返回ErrNotSorted。
+func FromSorted[K cmp.Ordered, V any](entries []Entry[K, V]) (*OrderedMap[K, V], error) {
+	return FromSortedFunc(cmp.Compare[K], entries)
+}
+
+// FromSortedFunc 与FromSorted相同，key通过compare比较，含义与NewOrderedFunc相同。
+func FromSortedFunc[K, V any](compare func(a, b K) int, entries []Entry[K, V]) (*OrderedMap[K, V], error) {
+	for i := 1; i < len(entries); i++ {
+		if compare(entries[i-1].Key, entries[i].Key) >= 0 {
+			return nil, ErrNotSorted
+		}
+	}
+	m := NewOrderedFunc[K, V](compare)
+	m.rebuild(entries)
+	return m, nil
+}
+
+// Clone 返回m的副本，复杂度为O(n)。
+func (m *OrderedMap[K, V]) Clone() *OrderedMap[K, V] {
+	c := *m
+	c.root = clone(m.root)
+	return &c
+}
+
+// Clear 删除所有元素。
+func (m *OrderedMap[K, V]) Clear() {
+	m.root = nil
+	m.length = 0
+}
+
+// Merge 把other中的所有元素加入m。key在两个map中都存在时，value为resolve(key, m中的value, other中的value)；
+// resolve为nil时取other中的value。两个map的key必须按相同的顺序排列。
+//
+// other较小时逐个插入，否则线性合并之后重建，复杂度为O(min(k log n, n+k))。
+func (m *OrderedMap[K, V]) Merge(other *OrderedMap[K, V], resolve func(key K, old, new V) V) {
+	if other.length*bits.Len(uint(m.length+other.length)) < m.length+other.length {
+		other.Do(func(key K, value V) {
+			if resolve != nil {
+				if old, found := m.Find(key); found {
+					value = resolve(key, old, value)
+				}
+			}
+			m.Insert(key, value)
+		})
+		return
+	}
+	m.rebuild(m.merge(other, resolve, true, true, true))
+}
+
+// Union 返回包含两个map中所有元素的新map，key冲突时的处理与Merge相同。复杂度为O(n+k)。
+func (m *OrderedMap[K, V]) Union(other *OrderedMap[K, V], resolve func(key K, a, b V) V) *OrderedMap[K, V] {
+	res := NewOrderedFunc[K, V](m.compare)
+	res.rebuild(m.merge(other, resolve, true, true, true))
+	return res
+}
+
+// Intersect 返回只包含两个map中都存在的key的新map，value为resolve(key, m中的value, other中的value)；
+// resolve为nil时取m中的value。复杂度为O(n+k)。
+func (m *OrderedMap[K, V]) Intersect(other *OrderedMap[K, V], resolve func(key K, a, b V) V) *OrderedMap[K, V] {
+	if resolve == nil {
+		resolve = func(_ K, a, _ V) V { return a }
+	}
+	res := NewOrderedFunc[K, V](m.compare)
+	res.rebuild(m.merge(other, resolve, false, true, false))
+	return res
+}
+
+// Difference 返回只包含m中存在、other中不存在的key的新map。复杂度为O(n+k)。
+func (m *OrderedMap[K, V]) Difference(other *OrderedMap[K, V]) *OrderedMap[K, V] {
+	res := NewOrderedFunc[K, V](m.compare)
+	res.rebuild(m.merge(other, nil, true, false, false))
+	return res
+}
+
+// merge 线性合并m与other，返回按key升序排列的元素：onlyM、both、onlyOther分别表示
+// 是否保留只在m中、两者都有、只在other中的key。both时value由resolve决定，resolve为nil时取other的value。
+func (m *OrderedMap[K, V]) merge(other *OrderedMap[K, V], resolve func(key K, a, b V) V,
+	onlyM, both, onlyOther bool) []Entry[K, V] {
+	res := make([]Entry[K, V], 0, m.length+other.length)
+	a, b := m.Iterator(), other.Iterator()
+	okA, okB := a.First(), b.First()
+	for okA || okB {
+		var c int
+		switch {
+		case !okB:
+			c = -1
+		case !okA:
+			c = 1
+		default:
+			c = m.compare(a.Key(), b.Key())
+		}
+		switch {
+		case c < 0:
+			if onlyM {
+				res = append(res, Entry[K, V]{a.Key(), a.Value()})
+			}
+			okA = a.Next()
+		case c > 0:
+			if onlyOther {
+				res = append(res, Entry[K, V]{b.Key(), b.Value()})
+			}
+			okB = b.Next()
+		default:
+			if both {
+				value := b.Value()
+				if resolve != nil {
+					value = resolve(a.Key(), a.Value(), value)
+				}
+				res = append(res, Entry[K, V]{a.Key(), value})
+			}
+			okA, okB = a.Next(), b.Next()
+		}
+	}
+	return res
+}
+
+// entries 返回按key升序排列的所有元素。
+func (m *OrderedMap[K, V]) entries() []Entry[K, V] {
+	res := make([]Entry[K, V], 0, m.length)
+	m.Do(func(key K, value V) {
+		res = append(res, Entry[K, V]{key, value})
+	})
+	return res
+}
+
+// rebuild 用按key严格升序排列的entries替换m中的所有元素，复杂度为O(n)。
+func (m *OrderedMap[K, V]) rebuild(entries []Entry[K, V]) {
+	m.root = build(entries, bits.Len(uint(len(entries)+1))-1)
+	m.length = len(entries)
+}
+
+// build 由有序的entries构造黑高为h的2-3红黑树，要求2^h-1 <= len(entries) <= 3^h-1。
+//
+// 元素足够少时根为2-节点（黑色节点与两棵黑高为h-1的子树），否则为3-节点
+// （黑色节点与红色的左子节点，共三棵黑高为h-1的子树），剩余的元素平均分给各子树。
+func build[K, V any](entries []Entry[K, V], h int) *node[K, V] {
+	n := len(entries)
+	if n == 0 {
+		return nil
+	}
+	if n-1 <= 2*maxSize(h-1) {
+		left := (n - 1) / 2
+		root := &node[K, V]{key: entries[left].Key, value: entries[left].Value}
+		root.left = build(entries[:left], h-1)
+		root.right = build(entries[left+1:], h-1)
+		root.resize()
+		return root
+	}
+	rest := n - 2
+	c1 := rest / 3
+	c2 := (rest - c1) / 2
+	red := &node[K, V]{key: entries[c1].Key, value: entries[c1].Value, red: true}
+	red.left = build(entries[:c1], h-1)
+	red.right = build(entries[c1+1:c1+1+c2], h-1)
+	red.resize()
+	root := &node[K, V]{key: entries[c1+1+c2].Key, value: entries[c1+1+c2].Value, left: red}
+	root.right = build(entries[c1+2+c2:], h-1)
+	root.resize()
+	return root
+}
+
+// maxSize 返回黑高为h的2-3红黑树最多的节点数3^h-1，溢出时返回最大的int。
+func maxSize(h int) int {
+	size := 1
+	for i := 0; i < h; i++ {
+		if size > (1<<62)/3 {
+			return int(^uint(0) >> 1)
+		}
+		size *= 3
+	}
+	return size - 1
+}
+
+// clone 复制以root为根的子树。
+func clone[K, V any](root *node[K, V]) *node[K, V] {
+	if root == nil {
+		return nil
+	}
+	c := *root
+	c.gen = 0
+	c.left, c.right = clone(root.left), clone(root.right)
+	return &c
+}
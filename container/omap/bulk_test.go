@@ -0,0 +1,192 @@
+package omap_test
+
+import (
+	"math/rand"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/alex023/basekit/container/omap"
+)
+
+func sortedEntries(keys ...int) []omap.Entry[int, int] {
+	entries := make([]omap.Entry[int, int], len(keys))
+	for i, k := range keys {
+		entries[i] = omap.Entry[int, int]{Key: k, Value: k * 10}
+	}
+	return entries
+}
+
+func mapKeys(m *omap.OrderedMap[int, int]) []int {
+	keys := []int{}
+	for k := range m.All() {
+		keys = append(keys, k)
+	}
+	return keys
+}
+
+func TestFromSorted(t *testing.T) {
+	r := rand.New(rand.NewSource(4))
+	for n := 0; n < 300; n++ {
+		keys := make([]int, n)
+		for i := range keys {
+			keys[i] = i * 3
+		}
+		m, err := omap.FromSorted(sortedEntries(keys...))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(mapKeys(m), keys) {
+			t.Fatalf("n=%d: unexpected keys %v", n, mapKeys(m))
+		}
+		for i, k := range keys {
+			if m.Rank(k) != i {
+				t.Fatalf("n=%d: Rank(%d) = %d", n, k, m.Rank(k))
+			}
+		}
+		// 构造出的树必须是合法的红黑树，之后的插入、删除才能正确执行
+		for i := 0; i < n; i++ {
+			m.Insert(r.Intn(3*n)*3+1, 0)
+		}
+		want := m.Len()
+		for _, i := range r.Perm(n) {
+			if !m.Delete(keys[i]) {
+				t.Fatalf("n=%d: failed to delete %d", n, keys[i])
+			}
+			want--
+		}
+		if m.Len() != want || len(mapKeys(m)) != want {
+			t.Fatalf("n=%d: map len %d should be %d", n, m.Len(), want)
+		}
+	}
+}
+
+func TestFromSortedUnsorted(t *testing.T) {
+	for _, keys := range [][]int{{1, 3, 2}, {1, 1}} {
+		if _, err := omap.FromSorted(sortedEntries(keys...)); err != omap.ErrNotSorted {
+			t.Errorf("FromSorted(%v) error = %v, should be ErrNotSorted", keys, err)
+		}
+	}
+	m, err := omap.FromSortedFunc(func(a, b string) int {
+		return strings.Compare(strings.ToLower(a), strings.ToLower(b))
+	}, []omap.Entry[string, int]{{"a", 1}, {"B", 2}, {"c", 3}})
+	if err != nil || m.Len() != 3 {
+		t.Fatalf("FromSortedFunc: %v, len %d", err, m.Len())
+	}
+	if v, ok := m.Find("b"); !ok || v != 2 {
+		t.Errorf("Find(b) = %d, %v", v, ok)
+	}
+}
+
+func TestSetOperations(t *testing.T) {
+	a, _ := omap.FromSorted(sortedEntries(1, 2, 3, 4, 5))
+	b, _ := omap.FromSorted(sortedEntries(4, 5, 6, 7))
+	b.Insert(5, 1)
+	sum := func(_, x, y int) int { return x + y }
+
+	u := a.Union(b, sum)
+	if !reflect.DeepEqual(mapKeys(u), []int{1, 2, 3, 4, 5, 6, 7}) {
+		t.Errorf("Union: %v", mapKeys(u))
+	}
+	if v, _ := u.Find(5); v != 51 {
+		t.Errorf("Union[5] = %d, should be 51", v)
+	}
+	if v, _ := a.Union(b, nil).Find(5); v != 1 {
+		t.Errorf("Union[5] = %d, should be 1", v)
+	}
+
+	i := a.Intersect(b, nil)
+	if !reflect.DeepEqual(mapKeys(i), []int{4, 5}) {
+		t.Errorf("Intersect: %v", mapKeys(i))
+	}
+	if v, _ := i.Find(5); v != 50 {
+		t.Errorf("Intersect[5] = %d, should be 50", v)
+	}
+	if v, _ := a.Intersect(b, sum).Find(5); v != 51 {
+		t.Errorf("Intersect[5] = %d, should be 51", v)
+	}
+
+	if d := a.Difference(b); !reflect.DeepEqual(mapKeys(d), []int{1, 2, 3}) {
+		t.Errorf("Difference: %v", mapKeys(d))
+	}
+	if a.Len() != 5 || b.Len() != 4 {
+		t.Errorf("set operations should not modify the operands")
+	}
+}
+
+func TestMerge(t *testing.T) {
+	// 分别覆盖逐个插入与线性合并两种方式
+	for _, size := range []int{2, 500} {
+		m := evenMap(1000)
+		other := omap.NewOrdered[int, int]()
+		for i := 0; i < size; i++ {
+			other.Insert(i*3, -1)
+		}
+		m.Merge(other, func(key, old, new int) int { return old + new })
+		want := make(map[int]int)
+		for i := 0; i < 1000; i++ {
+			want[2*i] = i
+		}
+		for i := 0; i < size; i++ {
+			want[i*3] += -1
+		}
+		checkOrdered(t, m, want)
+	}
+}
+
+func TestCloneClear(t *testing.T) {
+	m := evenMap(100)
+	c := m.Clone()
+	m.Clear()
+	if m.Len() != 0 || len(mapKeys(m)) != 0 {
+		t.Errorf("Clear left %d elements", m.Len())
+	}
+	if c.Len() != 100 || c.Rank(50) != 25 {
+		t.Errorf("clone has %d elements", c.Len())
+	}
+	c.Delete(50)
+	m.Insert(1, 1)
+	if c.Len() != 99 || m.Len() != 1 {
+		t.Errorf("clone should be independent")
+	}
+}
+
+func TestDeleteRangeRebuild(t *testing.T) {
+	m := evenMap(1000)
+	if n := m.DeleteRange(100, 1900); n != 900 {
+		t.Fatalf("deleted %d, should be 900", n)
+	}
+	want := make(map[int]int)
+	for i := 0; i < 1000; i++ {
+		if 2*i < 100 || 2*i >= 1900 {
+			want[2*i] = i
+		}
+	}
+	checkOrdered(t, m, want)
+	for k := range want {
+		if !m.Delete(k) {
+			t.Fatalf("failed to delete %d", k)
+		}
+	}
+}
+
+func BenchmarkFromSorted(b *testing.B) {
+	entries := make([]omap.Entry[int, int], 100000)
+	for i := range entries {
+		entries[i] = omap.Entry[int, int]{Key: i, Value: i}
+	}
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		omap.FromSorted(entries)
+	}
+}
+
+func BenchmarkInsertSorted(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		m := omap.NewOrdered[int, int]()
+		for k := 0; k < 100000; k++ {
+			m.Insert(k, k)
+		}
+	}
+}
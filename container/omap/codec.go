@@ -0,0 +1,228 @@
+package omap
+
+import (
+	"cmp"
+	"encoding/binary"
+	"encoding/json"
+	"math"
+)
+
+// Codec 定义类型T与字节序列之间的转换，用于MarshalBinary与GobEncode等二进制格式。
+// 通过OrderedMap.SetCodec为key与value分别指定。
+type Codec[T any] interface {
+	// Encode 把v的编码追加到dst之后，返回追加后的结果。
+	Encode(dst []byte, v T) ([]byte, error)
+	// Decode 从Encode生成的字节序列中还原T。
+	Decode(data []byte) (T, error)
+}
+
+// JSONCodec 通过encoding/json编解码T。
+type JSONCodec[T any] struct{}
+
+// Encode 实现Codec。
+func (JSONCodec[T]) Encode(dst []byte, v T) ([]byte, error) {
+	data, err := json.Marshal(v)
+	return append(dst, data...), err
+}
+
+// Decode 实现Codec。
+func (JSONCodec[T]) Decode(data []byte) (v T, err error) {
+	err = json.Unmarshal(data, &v)
+	return v, err
+}
+
+// AnyCodec 把Codec[T]适配为Map使用的Codec[interface{}]：编码时值的类型必须为T，
+// 否则返回ErrCodecType；解码得到T类型的值。Map的JSON解码不使用c，
+// 而是通过encoding/json解码为T。比如key为int的Map:
+//      m := omap.NewIntKeyed()
+//      m.SetCodec(omap.AnyCodec[int](omap.JSONCodec[int]{}), nil)
+func AnyCodec[T any](c Codec[T]) Codec[interface{}] {
+	return anyCodec[T]{c}
+}
+
+type anyCodec[T any] struct {
+	codec Codec[T]
+}
+
+// Encode 实现Codec。
+func (c anyCodec[T]) Encode(dst []byte, v interface{}) ([]byte, error) {
+	x, ok := v.(T)
+	if !ok {
+		return dst, ErrCodecType
+	}
+	return c.codec.Encode(dst, x)
+}
+
+// Decode 实现Codec。
+func (c anyCodec[T]) Decode(data []byte) (interface{}, error) {
+	v, err := c.codec.Decode(data)
+	if err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+// decodeJSON 通过encoding/json把data解码为T，用于Map.UnmarshalJSON。
+func (anyCodec[T]) decodeJSON(data []byte) (interface{}, error) {
+	var v T
+	if err := json.Unmarshal(data, &v); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+// jsonDecoder 由AnyCodec返回的Codec实现，为Map的JSON解码提供key或value的类型。
+type jsonDecoder interface {
+	decodeJSON(data []byte) (interface{}, error)
+}
+
+// basicCodec 是未指定Codec时的默认实现：string、[]byte、bool以及整数、浮点数类型
+// 采用紧凑的二进制编码，其他类型使用JSONCodec。
+type basicCodec[T any] struct{}
+
+// Encode 实现Codec。与Decode一样按照T本身而不是v的动态类型选择编码，
+// 因此T为interface{}时总是使用JSONCodec。
+func (basicCodec[T]) Encode(dst []byte, v T) ([]byte, error) {
+	switch p := any(&v).(type) {
+	case *string:
+		return append(dst, *p...), nil
+	case *[]byte:
+		return append(dst, *p...), nil
+	case *bool:
+		if *p {
+			return append(dst, 1), nil
+		}
+		return append(dst, 0), nil
+	case *int:
+		return binary.AppendVarint(dst, int64(*p)), nil
+	case *int8:
+		return binary.AppendVarint(dst, int64(*p)), nil
+	case *int16:
+		return binary.AppendVarint(dst, int64(*p)), nil
+	case *int32:
+		return binary.AppendVarint(dst, int64(*p)), nil
+	case *int64:
+		return binary.AppendVarint(dst, *p), nil
+	case *uint:
+		return binary.AppendUvarint(dst, uint64(*p)), nil
+	case *uint8:
+		return binary.AppendUvarint(dst, uint64(*p)), nil
+	case *uint16:
+		return binary.AppendUvarint(dst, uint64(*p)), nil
+	case *uint32:
+		return binary.AppendUvarint(dst, uint64(*p)), nil
+	case *uint64:
+		return binary.AppendUvarint(dst, *p), nil
+	case *float32:
+		return binary.BigEndian.AppendUint32(dst, math.Float32bits(*p)), nil
+	case *float64:
+		return binary.BigEndian.AppendUint64(dst, math.Float64bits(*p)), nil
+	}
+	return JSONCodec[T]{}.Encode(dst, v)
+}
+
+// Decode 实现Codec。
+func (basicCodec[T]) Decode(data []byte) (v T, err error) {
+	varint := func() int64 {
+		x, n := binary.Varint(data)
+		if n != len(data) {
+			err = ErrBadData
+		}
+		return x
+	}
+	uvarint := func() uint64 {
+		x, n := binary.Uvarint(data)
+		if n != len(data) {
+			err = ErrBadData
+		}
+		return x
+	}
+	switch p := any(&v).(type) {
+	case *string:
+		*p = string(data)
+	case *[]byte:
+		*p = append([]byte(nil), data...)
+	case *bool:
+		if len(data) != 1 || data[0] > 1 {
+			return v, ErrBadData
+		}
+		*p = data[0] == 1
+	case *int:
+		*p = int(varint())
+	case *int8:
+		*p = int8(varint())
+	case *int16:
+		*p = int16(varint())
+	case *int32:
+		*p = int32(varint())
+	case *int64:
+		*p = varint()
+	case *uint:
+		*p = uint(uvarint())
+	case *uint8:
+		*p = uint8(uvarint())
+	case *uint16:
+		*p = uint16(uvarint())
+	case *uint32:
+		*p = uint32(uvarint())
+	case *uint64:
+		*p = uvarint()
+	case *float32:
+		if len(data) != 4 {
+			return v, ErrBadData
+		}
+		*p = math.Float32frombits(binary.BigEndian.Uint32(data))
+	case *float64:
+		if len(data) != 8 {
+			return v, ErrBadData
+		}
+		*p = math.Float64frombits(binary.BigEndian.Uint64(data))
+	default:
+		return JSONCodec[T]{}.Decode(data)
+	}
+	return v, err
+}
+
+// orderedCompare 返回K的默认比较函数：K为预定义的整数、浮点数或string类型时返回cmp.Compare[K]；
+// 否则返回nil，比如type ID int这样的命名类型，需要通过NewOrdered或NewOrderedFunc创建map。
+// 用于把数据解码到零值的map中。
+func orderedCompare[K any]() func(a, b K) int {
+	var zero K
+	switch any(zero).(type) {
+	case string:
+		return compareAs[K, string]()
+	case int:
+		return compareAs[K, int]()
+	case int8:
+		return compareAs[K, int8]()
+	case int16:
+		return compareAs[K, int16]()
+	case int32:
+		return compareAs[K, int32]()
+	case int64:
+		return compareAs[K, int64]()
+	case uint:
+		return compareAs[K, uint]()
+	case uint8:
+		return compareAs[K, uint8]()
+	case uint16:
+		return compareAs[K, uint16]()
+	case uint32:
+		return compareAs[K, uint32]()
+	case uint64:
+		return compareAs[K, uint64]()
+	case uintptr:
+		return compareAs[K, uintptr]()
+	case float32:
+		return compareAs[K, float32]()
+	case float64:
+		return compareAs[K, float64]()
+	}
+	return nil
+}
+
+// compareAs 在K与T是同一类型时返回cmp.Compare[T]。
+func compareAs[K any, T cmp.Ordered]() func(a, b K) int {
+	f, _ := any(cmp.Compare[T]).(func(a, b K) int)
+	return f
+}
@@ -0,0 +1,192 @@
+package omap
+
+import (
+	"cmp"
+	"iter"
+	"sync"
+	"sync/atomic"
+)
+
+// ConcurrentMap 是goroutine安全的有序map，方法与OrderedMap相同。
+//
+// 修改操作之间通过互斥锁串行执行：每次修改采用路径复制，生成新版本的树（只复制O(log n)个节点），
+// 再原子地替换当前版本。读操作只访问某一个版本的树，既不加锁，也不会阻塞修改操作；
+// 迭代器与Range、Do等遍历方法看到的是开始遍历时的版本，遍历期间的修改对它们不可见。
+//
+// The zero value is an invalid map! 通过NewConcurrent或NewConcurrentFunc创建。
+type ConcurrentMap[K, V any] struct {
+	mu      sync.Mutex
+	current atomic.Pointer[OrderedMap[K, V]] // 已发布的版本，不会再被修改
+}
+
+// NewConcurrent 返回一个空的ConcurrentMap,其key按照cmp.Compare的顺序排列。
+func NewConcurrent[K cmp.Ordered, V any]() *ConcurrentMap[K, V] {
+	return NewConcurrentFunc[K, V](cmp.Compare[K])
+}
+
+// NewConcurrentFunc 返回一个空的ConcurrentMap,key通过compare比较，含义与NewOrderedFunc相同。
+func NewConcurrentFunc[K, V any](compare func(a, b K) int) *ConcurrentMap[K, V] {
+	m := &ConcurrentMap[K, V]{}
+	m.current.Store(NewOrderedFunc[K, V](compare))
+	return m
+}
+
+// update 在写锁内基于当前版本执行fn，fn返回true时发布修改后的版本。
+func (m *ConcurrentMap[K, V]) update(fn func(next *OrderedMap[K, V], t txn[K, V]) bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	next := *m.current.Load()
+	if fn(&next, txn[K, V]{compare: next.compare, gen: generation.Add(1)}) {
+		m.current.Store(&next)
+	}
+}
+
+// Insert 插入或者替换key对应的value，含义与OrderedMap.Insert相同。
+func (m *ConcurrentMap[K, V]) Insert(key K, value V) (inserted bool) {
+	m.update(func(next *OrderedMap[K, V], t txn[K, V]) bool {
+		inserted = next.insertWith(t, key, value)
+		return true
+	})
+	return inserted
+}
+
+// Delete 删除key对应的元素，含义与OrderedMap.Delete相同。
+func (m *ConcurrentMap[K, V]) Delete(key K) (deleted bool) {
+	m.update(func(next *OrderedMap[K, V], t txn[K, V]) bool {
+		if _, found := next.Find(key); found {
+			deleted = next.deleteWith(t, key)
+		}
+		return deleted
+	})
+	return deleted
+}
+
+// DeleteRange 原子地删除区间内的所有元素，返回删除的数量，区间的含义与Range相同。
+func (m *ConcurrentMap[K, V]) DeleteRange(lo, hi K, opts ...RangeOption) (n int) {
+	m.update(func(next *OrderedMap[K, V], t txn[K, V]) bool {
+		keys := make([]K, 0, next.CountRange(lo, hi, opts...))
+		next.Range(lo, hi, func(key K, _ V) bool {
+			keys = append(keys, key)
+			return true
+		}, opts...)
+		for _, key := range keys {
+			next.deleteWith(t, key)
+		}
+		n = len(keys)
+		return n > 0
+	})
+	return n
+}
+
+// PopFirst 原子地删除并返回key最小的元素，map为空时found为false。
+func (m *ConcurrentMap[K, V]) PopFirst() (key K, value V, found bool) {
+	m.update(func(next *OrderedMap[K, V], t txn[K, V]) bool {
+		if key, value, found = next.First(); found {
+			next.deleteWith(t, key)
+		}
+		return found
+	})
+	return key, value, found
+}
+
+// PopLast 原子地删除并返回key最大的元素，map为空时found为false。
+func (m *ConcurrentMap[K, V]) PopLast() (key K, value V, found bool) {
+	m.update(func(next *OrderedMap[K, V], t txn[K, V]) bool {
+		if key, value, found = next.Latest(); found {
+			next.deleteWith(t, key)
+		}
+		return found
+	})
+	return key, value, found
+}
+
+// Find 返回key对应的value。
+func (m *ConcurrentMap[K, V]) Find(key K) (value V, found bool) {
+	return m.current.Load().Find(key)
+}
+
+// First 返回key最小的元素。
+func (m *ConcurrentMap[K, V]) First() (key K, value V, found bool) {
+	return m.current.Load().First()
+}
+
+// Latest 返回key最大的元素。
+func (m *ConcurrentMap[K, V]) Latest() (key K, value V, found bool) {
+	return m.current.Load().Latest()
+}
+
+// Floor 返回小于等于key的最大元素。
+func (m *ConcurrentMap[K, V]) Floor(key K) (k K, value V, found bool) {
+	return m.current.Load().Floor(key)
+}
+
+// Ceiling 返回大于等于key的最小元素。
+func (m *ConcurrentMap[K, V]) Ceiling(key K) (k K, value V, found bool) {
+	return m.current.Load().Ceiling(key)
+}
+
+// Lower 返回严格小于key的最大元素。
+func (m *ConcurrentMap[K, V]) Lower(key K) (k K, value V, found bool) {
+	return m.current.Load().Lower(key)
+}
+
+// Higher 返回严格大于key的最小元素。
+func (m *ConcurrentMap[K, V]) Higher(key K) (k K, value V, found bool) {
+	return m.current.Load().Higher(key)
+}
+
+// Len 返回map中的键值对数量。
+func (m *ConcurrentMap[K, V]) Len() int {
+	return m.current.Load().Len()
+}
+
+// Rank 返回map中小于key的元素数量。
+func (m *ConcurrentMap[K, V]) Rank(key K) int {
+	return m.current.Load().Rank(key)
+}
+
+// Select 返回按升序排列的第k个元素（从0开始）。
+func (m *ConcurrentMap[K, V]) Select(k int) (key K, value V, found bool) {
+	return m.current.Load().Select(k)
+}
+
+// CountRange 返回区间内的元素数量。
+func (m *ConcurrentMap[K, V]) CountRange(lo, hi K, opts ...RangeOption) int {
+	return m.current.Load().CountRange(lo, hi, opts...)
+}
+
+// Do 按key升序对当前版本的每个元素调用function。
+func (m *ConcurrentMap[K, V]) Do(function func(K, V)) {
+	m.current.Load().Do(function)
+}
+
+// Range 按顺序对当前版本中区间内的每个元素调用fn，fn返回false时停止遍历。
+func (m *ConcurrentMap[K, V]) Range(lo, hi K, fn func(key K, value V) bool, opts ...RangeOption) {
+	m.current.Load().Range(lo, hi, fn, opts...)
+}
+
+// Iterator 返回当前版本上的一个未定位的迭代器，之后的修改对它不可见。
+func (m *ConcurrentMap[K, V]) Iterator() *Iterator[K, V] {
+	return m.current.Load().Iterator()
+}
+
+// All 返回按key升序遍历的迭代器，每次遍历都从当时的版本开始。
+func (m *ConcurrentMap[K, V]) All() iter.Seq2[K, V] {
+	return func(yield func(K, V) bool) {
+		m.current.Load().All()(yield)
+	}
+}
+
+// Backward 返回按key降序遍历的迭代器，每次遍历都从当时的版本开始。
+func (m *ConcurrentMap[K, V]) Backward() iter.Seq2[K, V] {
+	return func(yield func(K, V) bool) {
+		m.current.Load().Backward()(yield)
+	}
+}
+
+// From 返回从大于等于key的最小元素开始按key升序遍历的迭代器，每次遍历都从当时的版本开始。
+func (m *ConcurrentMap[K, V]) From(key K) iter.Seq2[K, V] {
+	return func(yield func(K, V) bool) {
+		m.current.Load().From(key)(yield)
+	}
+}
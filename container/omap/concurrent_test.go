@@ -0,0 +1,118 @@
+package omap_test
+
+import (
+	"math/rand"
+	"sync"
+	"testing"
+
+	"github.com/alex023/basekit/container/omap"
+)
+
+func TestConcurrentMapBasic(t *testing.T) {
+	m := omap.NewConcurrent[int, int]()
+	for _, n := range []int{5, 1, 4, 2, 3} {
+		if !m.Insert(n, n*10) {
+			t.Errorf("Insert(%d) should insert", n)
+		}
+	}
+	if m.Insert(3, 33) {
+		t.Error("Insert(3) should replace")
+	}
+	if v, ok := m.Find(3); !ok || v != 33 {
+		t.Errorf("Find(3) = %d, %v", v, ok)
+	}
+	if m.Delete(9) || !m.Delete(4) || m.Len() != 4 {
+		t.Errorf("unexpected Delete result, len %d", m.Len())
+	}
+	if k, _, ok := m.PopFirst(); !ok || k != 1 {
+		t.Errorf("PopFirst() = %d, %v", k, ok)
+	}
+	if k, _, ok := m.PopLast(); !ok || k != 5 {
+		t.Errorf("PopLast() = %d, %v", k, ok)
+	}
+	if k, _, ok := m.Select(1); !ok || k != 3 || m.Rank(3) != 1 {
+		t.Errorf("Select(1) = %d, %v", k, ok)
+	}
+	if n := m.DeleteRange(0, 10); n != 2 || m.Len() != 0 {
+		t.Errorf("DeleteRange deleted %d, %d left", n, m.Len())
+	}
+}
+
+func TestConcurrentMapSnapshot(t *testing.T) {
+	m := omap.NewConcurrent[int, int]()
+	for i := 0; i < 100; i++ {
+		m.Insert(i, i)
+	}
+	it := m.Iterator()
+	count := 0
+	for ok := it.First(); ok; ok = it.Next() {
+		if count%10 == 0 {
+			// 遍历期间的修改对迭代器不可见
+			m.Delete(count + 1)
+			m.Insert(1000+count, 0)
+		}
+		if it.Key() != count {
+			t.Fatalf("got %d, should be %d", it.Key(), count)
+		}
+		count++
+	}
+	if count != 100 {
+		t.Errorf("visited %d, should be 100", count)
+	}
+	if m.Len() != 100 {
+		t.Errorf("map len %d should be 100", m.Len())
+	}
+}
+
+func TestConcurrentMapRace(t *testing.T) {
+	m := omap.NewConcurrent[int, int]()
+	var wg sync.WaitGroup
+	for w := 0; w < 4; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			r := rand.New(rand.NewSource(int64(w)))
+			for i := 0; i < 2000; i++ {
+				k := r.Intn(500)
+				if r.Intn(2) == 0 {
+					m.Insert(k, k)
+				} else {
+					m.Delete(k)
+				}
+			}
+		}(w)
+	}
+	for g := 0; g < 4; g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 200; i++ {
+				// 每次遍历都是一个一致的版本：升序，且数量与版本的Len一致
+				prev, count := -1, 0
+				for k, v := range m.All() {
+					if k <= prev || k != v {
+						t.Errorf("inconsistent pair %d=%d after %d", k, v, prev)
+						return
+					}
+					prev = k
+					count++
+				}
+				m.Find(i)
+			}
+		}()
+	}
+	wg.Wait()
+	count := 0
+	m.Do(func(_, _ int) { count++ })
+	if count != m.Len() {
+		t.Errorf("visited %d, len %d", count, m.Len())
+	}
+}
+
+func BenchmarkConcurrentMapInsert(b *testing.B) {
+	m := omap.NewConcurrent[int, int]()
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		m.Insert(i%100000, i)
+	}
+}
@@ -0,0 +1,251 @@
+package omap
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+)
+
+var (
+	// ErrBadData 表示要解码的数据格式不正确。
+	ErrBadData = errors.New("omap: malformed data")
+	// ErrNoCompare 表示解码的目标是零值的map，并且key不是预定义的整数、浮点数或string类型，
+	// 无法确定默认的比较函数。
+	ErrNoCompare = errors.New("omap: map has no compare function")
+	// ErrNoCodec 表示解码Map之前没有通过SetCodec指定key的Codec，
+	// 或者JSON解码时key的Codec不是由AnyCodec创建的。
+	ErrNoCodec = errors.New("omap: Map has no key codec")
+	// ErrCodecType 表示AnyCodec编码的值与其类型参数不一致。
+	ErrCodecType = errors.New("omap: value type does not match codec")
+)
+
+// 二进制格式的魔数与格式版本
+const (
+	binaryMagic         = "OMAP"
+	binaryFormatVersion = 1
+)
+
+// SetCodec 指定MarshalBinary、UnmarshalBinary以及gob编解码时key与value使用的Codec，
+// 参数为nil时使用默认的编码：string、[]byte、bool以及整数、浮点数类型采用紧凑的二进制编码，
+// 其他类型使用JSONCodec。
+//
+// Map的key与value为interface{}，默认编码无法还原原有的类型，解码前必须指定key的Codec，
+// 通常通过AnyCodec适配，见Map.UnmarshalJSON。
+func (m *OrderedMap[K, V]) SetCodec(key Codec[K], value Codec[V]) {
+	m.keyCodec, m.valueCodec = key, value
+}
+
+// codecs 返回key与value实际使用的Codec。
+func (m *OrderedMap[K, V]) codecs() (Codec[K], Codec[V]) {
+	var (
+		key   Codec[K] = basicCodec[K]{}
+		value Codec[V] = basicCodec[V]{}
+	)
+	if m.keyCodec != nil {
+		key = m.keyCodec
+	}
+	if m.valueCodec != nil {
+		value = m.valueCodec
+	}
+	return key, value
+}
+
+// MarshalBinary 实现encoding.BinaryMarshaler。数据依次为魔数、格式版本、元素数量，
+// 以及按key升序排列的每个元素：带长度前缀的key与value的编码。
+func (m *OrderedMap[K, V]) MarshalBinary() ([]byte, error) {
+	keyCodec, valueCodec := m.codecs()
+	buf := append([]byte(binaryMagic), binaryFormatVersion)
+	buf = binary.AppendUvarint(buf, uint64(m.length))
+	var (
+		scratch []byte
+		err     error
+	)
+	appendField := func(encode func([]byte) ([]byte, error)) {
+		if err != nil {
+			return
+		}
+		if scratch, err = encode(scratch[:0]); err == nil {
+			buf = binary.AppendUvarint(buf, uint64(len(scratch)))
+			buf = append(buf, scratch...)
+		}
+	}
+	m.Do(func(key K, value V) {
+		appendField(func(dst []byte) ([]byte, error) { return keyCodec.Encode(dst, key) })
+		appendField(func(dst []byte) ([]byte, error) { return valueCodec.Encode(dst, value) })
+	})
+	if err != nil {
+		return nil, err
+	}
+	return buf, nil
+}
+
+// UnmarshalBinary 实现encoding.BinaryUnmarshaler，用MarshalBinary导出的数据替换m中的所有元素。
+// 数据按key升序排列时以O(n)的代价重建红黑树。解码失败时m保持不变。
+func (m *OrderedMap[K, V]) UnmarshalBinary(data []byte) error {
+	if len(data) < len(binaryMagic)+1 || string(data[:len(binaryMagic)]) != binaryMagic ||
+		data[len(binaryMagic)] != binaryFormatVersion {
+		return ErrBadData
+	}
+	data = data[len(binaryMagic)+1:]
+	count, n := binary.Uvarint(data)
+	if n <= 0 || count > uint64(len(data)) {
+		return ErrBadData
+	}
+	data = data[n:]
+	next := func() ([]byte, error) {
+		l, n := binary.Uvarint(data)
+		if n <= 0 || l > uint64(len(data)-n) {
+			return nil, ErrBadData
+		}
+		field := data[n : n+int(l)]
+		data = data[n+int(l):]
+		return field, nil
+	}
+	keyCodec, valueCodec := m.codecs()
+	entries := make([]Entry[K, V], count)
+	for i := range entries {
+		field, err := next()
+		if err != nil {
+			return err
+		}
+		if entries[i].Key, err = keyCodec.Decode(field); err != nil {
+			return err
+		}
+		if field, err = next(); err != nil {
+			return err
+		}
+		if entries[i].Value, err = valueCodec.Decode(field); err != nil {
+			return err
+		}
+	}
+	if len(data) != 0 {
+		return ErrBadData
+	}
+	return m.load(entries)
+}
+
+// GobEncode 实现gob.GobEncoder，格式与MarshalBinary相同。
+func (m *OrderedMap[K, V]) GobEncode() ([]byte, error) {
+	return m.MarshalBinary()
+}
+
+// GobDecode 实现gob.GobDecoder，格式与UnmarshalBinary相同。
+func (m *OrderedMap[K, V]) GobDecode(data []byte) error {
+	return m.UnmarshalBinary(data)
+}
+
+// MarshalJSON 实现json.Marshaler。map导出为按key升序排列的[key, value]数组，
+// 比如[[1,"a"],[2,"b"]]，key与value通过encoding/json编码。
+func (m *OrderedMap[K, V]) MarshalJSON() ([]byte, error) {
+	var (
+		buf bytes.Buffer
+		err error
+	)
+	enc := json.NewEncoder(&buf)
+	buf.WriteByte('[')
+	m.Do(func(key K, value V) {
+		if err != nil {
+			return
+		}
+		if buf.Len() > 1 {
+			buf.WriteByte(',')
+		}
+		if err = enc.Encode([2]any{key, value}); err == nil {
+			buf.Truncate(buf.Len() - 1) // Encode在末尾追加了换行
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	buf.WriteByte(']')
+	return buf.Bytes(), nil
+}
+
+// UnmarshalJSON 实现json.Unmarshaler，用MarshalJSON导出的数据替换m中的所有元素。
+// 数据按key升序排列时以O(n)的代价重建红黑树。解码失败时m保持不变。
+func (m *OrderedMap[K, V]) UnmarshalJSON(data []byte) error {
+	var raw [][2]json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	entries := make([]Entry[K, V], len(raw))
+	for i, pair := range raw {
+		if err := json.Unmarshal(pair[0], &entries[i].Key); err != nil {
+			return err
+		}
+		if err := json.Unmarshal(pair[1], &entries[i].Value); err != nil {
+			return err
+		}
+	}
+	return m.load(entries)
+}
+
+// load 用解码得到的entries替换m中的所有元素。entries按key严格升序排列时以O(n)的代价重建，
+// 否则逐个插入，key重复时后面的元素生效。
+func (m *OrderedMap[K, V]) load(entries []Entry[K, V]) error {
+	if m.compare == nil {
+		if m.compare = orderedCompare[K](); m.compare == nil {
+			return ErrNoCompare
+		}
+	}
+	for i := 1; i < len(entries); i++ {
+		if m.compare(entries[i-1].Key, entries[i].Key) >= 0 {
+			m.Clear()
+			for _, e := range entries {
+				m.Insert(e.Key, e.Value)
+			}
+			return nil
+		}
+	}
+	m.rebuild(entries)
+	return nil
+}
+
+// UnmarshalBinary 实现encoding.BinaryUnmarshaler，与OrderedMap.UnmarshalBinary相同，
+// 但要求已经通过SetCodec指定了key的Codec，否则返回ErrNoCodec：
+// 默认编码会把key还原为float64等JSON类型，与less期望的类型不一致。
+func (m *Map) UnmarshalBinary(data []byte) error {
+	if m.keyCodec == nil {
+		return ErrNoCodec
+	}
+	return m.OrderedMap.UnmarshalBinary(data)
+}
+
+// GobDecode 实现gob.GobDecoder，格式与UnmarshalBinary相同。
+func (m *Map) GobDecode(data []byte) error {
+	return m.UnmarshalBinary(data)
+}
+
+// UnmarshalJSON 实现json.Unmarshaler。与MarshalJSON一样，key与value通过encoding/json解码，
+// 不使用Codec本身的编码；解码的类型由SetCodec指定的AnyCodec的类型参数决定。比如:
+//      m := omap.NewIntKeyed()
+//      m.SetCodec(omap.AnyCodec[int](omap.JSONCodec[int]{}), omap.AnyCodec[string](omap.JSONCodec[string]{}))
+//      err := json.Unmarshal(data, m)
+// key的Codec不是由AnyCodec创建时返回ErrNoCodec；value的Codec不是由AnyCodec创建时，
+// value按照encoding/json的默认类型解码。
+func (m *Map) UnmarshalJSON(data []byte) error {
+	key, ok := m.keyCodec.(jsonDecoder)
+	if !ok {
+		return ErrNoCodec
+	}
+	value, ok := m.valueCodec.(jsonDecoder)
+	if !ok {
+		value = anyCodec[interface{}]{}
+	}
+	var raw [][2]json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	entries := make([]Entry[interface{}, interface{}], len(raw))
+	for i, pair := range raw {
+		var err error
+		if entries[i].Key, err = key.decodeJSON(pair[0]); err != nil {
+			return err
+		}
+		if entries[i].Value, err = value.decodeJSON(pair[1]); err != nil {
+			return err
+		}
+	}
+	return m.load(entries)
+}
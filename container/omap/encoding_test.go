@@ -0,0 +1,296 @@
+package omap_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/gob"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"testing"
+
+	"github.com/alex023/basekit/container/omap"
+)
+
+func ExampleOrderedMap_MarshalJSON() {
+	m := omap.NewOrdered[int, string]()
+	m.Insert(10, "ten")
+	m.Insert(2, "two")
+	data, _ := json.Marshal(m)
+	fmt.Println(string(data))
+
+	// Output:
+	// [[2,"two"],[10,"ten"]]
+}
+
+func TestBinaryRoundTrip(t *testing.T) {
+	m := evenMap(1000)
+	data, err := m.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var res omap.OrderedMap[int, int] // 零值的map使用默认的比较函数
+	if err := res.UnmarshalBinary(data); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(mapKeys(&res), mapKeys(m)) {
+		t.Fatalf("unexpected keys %v", mapKeys(&res))
+	}
+	for k, v := range m.All() {
+		if got, ok := res.Find(k); !ok || got != v {
+			t.Fatalf("Find(%d) = %d, %v, should be %d", k, got, ok, v)
+		}
+	}
+	// 解码得到的树可以继续修改
+	for k := range m.All() {
+		if !res.Delete(k) {
+			t.Fatalf("failed to delete %d", k)
+		}
+	}
+
+	for _, bad := range [][]byte{nil, []byte("OMAP"), data[:len(data)-1], append(data, 0)} {
+		if err := res.UnmarshalBinary(bad); err != omap.ErrBadData {
+			t.Errorf("UnmarshalBinary(%q) error = %v, should be ErrBadData", bad, err)
+		}
+	}
+}
+
+func TestBinaryTypes(t *testing.T) {
+	type point struct{ X, Y int }
+	m := omap.NewOrdered[float64, point]()
+	m.Insert(-1.5, point{1, 2})
+	m.Insert(3.25, point{3, 4})
+	data, err := m.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	res := omap.NewOrdered[float64, point]()
+	res.Insert(100, point{})
+	if err := res.UnmarshalBinary(data); err != nil {
+		t.Fatal(err)
+	}
+	if v, ok := res.Find(3.25); !ok || v != (point{3, 4}) || res.Len() != 2 {
+		t.Errorf("Find(3.25) = %v, %v, len %d", v, ok, res.Len())
+	}
+}
+
+// decimalCodec 把int编码为十进制字符串。
+type decimalCodec struct{}
+
+func (decimalCodec) Encode(dst []byte, v int) ([]byte, error) {
+	return strconv.AppendInt(dst, int64(v), 10), nil
+}
+
+func (decimalCodec) Decode(data []byte) (int, error) {
+	return strconv.Atoi(string(data))
+}
+
+func TestCustomCodec(t *testing.T) {
+	m := omap.NewOrdered[int, int]()
+	m.SetCodec(decimalCodec{}, decimalCodec{})
+	m.Insert(12, 345)
+	data, err := m.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Contains(data, []byte("\x0212\x03345")) {
+		t.Errorf("custom codec not used: %q", data)
+	}
+	res := omap.NewOrdered[int, int]()
+	res.SetCodec(decimalCodec{}, decimalCodec{})
+	if err := res.UnmarshalBinary(data); err != nil {
+		t.Fatal(err)
+	}
+	if v, ok := res.Find(12); !ok || v != 345 {
+		t.Errorf("Find(12) = %d, %v", v, ok)
+	}
+}
+
+func TestMapRoundTrip(t *testing.T) {
+	m := omap.NewIntKeyed()
+	m.Insert(2, "b")
+	m.Insert(1, "a")
+	jsonData, err := json.Marshal(m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 未指定Codec时，默认编码把interface{}编码为JSON
+	binData, err := m.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	newMap := func() *omap.Map {
+		res := omap.NewIntKeyed()
+		res.SetCodec(omap.AnyCodec[int](omap.JSONCodec[int]{}), omap.AnyCodec[string](omap.JSONCodec[string]{}))
+		return res
+	}
+	decoders := []struct {
+		name   string
+		decode func(*omap.Map) error
+	}{
+		{"JSON", func(res *omap.Map) error { return json.Unmarshal(jsonData, res) }},
+		{"binary", func(res *omap.Map) error { return res.UnmarshalBinary(binData) }},
+		{"gob", func(res *omap.Map) error { return res.GobDecode(binData) }},
+	}
+	for _, d := range decoders {
+		res := newMap()
+		if err := d.decode(res); err != nil {
+			t.Fatalf("%s: %v", d.name, err)
+		}
+		if v, ok := res.Find(2); !ok || v != "b" || res.Len() != 2 {
+			t.Errorf("%s: Find(2) = %v, %v, len %d", d.name, v, ok, res.Len())
+		}
+		// 没有指定key的Codec时返回错误，而不是在less中panic
+		if err := d.decode(omap.NewIntKeyed()); err != omap.ErrNoCodec {
+			t.Errorf("%s: error = %v, should be ErrNoCodec", d.name, err)
+		}
+	}
+
+	m.SetCodec(omap.AnyCodec[string](omap.JSONCodec[string]{}), nil)
+	if _, err := m.MarshalBinary(); err != omap.ErrCodecType {
+		t.Errorf("error = %v, should be ErrCodecType", err)
+	}
+}
+
+// varintCodec 把int编码为varint，其编码不是合法的JSON。
+type varintCodec struct{}
+
+func (varintCodec) Encode(dst []byte, v int) ([]byte, error) {
+	return binary.AppendVarint(dst, int64(v)), nil
+}
+
+func (varintCodec) Decode(data []byte) (int, error) {
+	v, n := binary.Varint(data)
+	if n != len(data) {
+		return 0, omap.ErrBadData
+	}
+	return int(v), nil
+}
+
+// JSON编解码都不使用Codec本身的编码，非JSON的Codec也能往返
+func TestMapNonJSONCodec(t *testing.T) {
+	newMap := func() *omap.Map {
+		m := omap.NewIntKeyed()
+		m.SetCodec(omap.AnyCodec[int](varintCodec{}), omap.AnyCodec[string](omap.JSONCodec[string]{}))
+		return m
+	}
+	m := newMap()
+	m.Insert(300, "b")
+	m.Insert(1, "a")
+	jsonData, err := json.Marshal(m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `[[1,"a"],[300,"b"]]`; string(jsonData) != want {
+		t.Errorf("got %s, should be %s", jsonData, want)
+	}
+	binData, err := m.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoders := []struct {
+		name   string
+		decode func(*omap.Map) error
+	}{
+		{"JSON", func(res *omap.Map) error { return json.Unmarshal(jsonData, res) }},
+		{"binary", func(res *omap.Map) error { return res.UnmarshalBinary(binData) }},
+	}
+	for _, d := range decoders {
+		res := newMap()
+		if err := d.decode(res); err != nil {
+			t.Fatalf("%s: %v", d.name, err)
+		}
+		var keys []interface{}
+		res.Do(func(k, _ interface{}) { keys = append(keys, k) })
+		if !reflect.DeepEqual(keys, []interface{}{1, 300}) {
+			t.Errorf("%s: keys %v, should be [1 300]", d.name, keys)
+		}
+		if v, _ := res.Find(300); v != "b" {
+			t.Errorf("%s: Find(300) = %v", d.name, v)
+		}
+	}
+}
+
+func TestJSONRoundTrip(t *testing.T) {
+	m := omap.NewOrdered[string, int]()
+	for i, s := range []string{"pear", "apple", "fig"} {
+		m.Insert(s, i)
+	}
+	data, err := json.Marshal(m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `[["apple",1],["fig",2],["pear",0]]`; string(data) != want {
+		t.Errorf("got %s, should be %s", data, want)
+	}
+	var res omap.OrderedMap[string, int]
+	if err := json.Unmarshal(data, &res); err != nil {
+		t.Fatal(err)
+	}
+	if v, ok := res.Find("fig"); !ok || v != 2 || res.Len() != 3 {
+		t.Errorf("Find(fig) = %d, %v, len %d", v, ok, res.Len())
+	}
+	// 未排序、有重复key的数据逐个插入，后面的元素生效
+	if err := json.Unmarshal([]byte(`[["b",1],["a",2],["b",3]]`), &res); err != nil {
+		t.Fatal(err)
+	}
+	if v, _ := res.Find("b"); v != 3 || res.Len() != 2 {
+		t.Errorf("Find(b) = %d, len %d", v, res.Len())
+	}
+	if err := json.Unmarshal([]byte(`{"a":1}`), &res); err == nil {
+		t.Error("expected an error for an object")
+	}
+	if data, _ := json.Marshal(omap.NewOrdered[int, int]()); string(data) != "[]" {
+		t.Errorf("empty map encoded as %s", data)
+	}
+}
+
+func TestGob(t *testing.T) {
+	type table struct {
+		Name  string
+		Index *omap.OrderedMap[string, int]
+	}
+	in := table{Name: "t", Index: omap.NewOrdered[string, int]()}
+	in.Index.Insert("x", 1)
+	in.Index.Insert("y", 2)
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(in); err != nil {
+		t.Fatal(err)
+	}
+	var out table
+	if err := gob.NewDecoder(&buf).Decode(&out); err != nil {
+		t.Fatal(err)
+	}
+	if out.Name != "t" || out.Index.Len() != 2 {
+		t.Fatalf("unexpected result %+v", out)
+	}
+	if v, ok := out.Index.Find("y"); !ok || v != 2 {
+		t.Errorf("Find(y) = %d, %v", v, ok)
+	}
+}
+
+func TestNoCompare(t *testing.T) {
+	type key struct{ A int }
+	var m omap.OrderedMap[key, int]
+	if err := m.UnmarshalJSON([]byte(`[[{"A":1},1]]`)); err != omap.ErrNoCompare {
+		t.Errorf("error = %v, should be ErrNoCompare", err)
+	}
+	// 命名类型需要通过NewOrdered创建
+	type id int
+	var ids omap.OrderedMap[id, int]
+	if err := ids.UnmarshalJSON([]byte(`[[1,1]]`)); err != omap.ErrNoCompare {
+		t.Errorf("error = %v, should be ErrNoCompare", err)
+	}
+	named := omap.NewOrdered[id, int]()
+	if err := named.UnmarshalJSON([]byte(`[[2,1],[1,2]]`)); err != nil || named.Len() != 2 {
+		t.Errorf("error = %v, len %d", err, named.Len())
+	}
+	var u omap.OrderedMap[uint16, int]
+	if err := u.UnmarshalJSON([]byte(`[[2,1],[1,2]]`)); err != nil {
+		t.Fatal(err)
+	}
+	if k, _, _ := u.First(); k != 1 {
+		t.Errorf("First() = %d, should be 1", k)
+	}
+}
@@ -0,0 +1,205 @@
+package omap
+
+import "iter"
+
+// Iterator 是OrderedMap上的双向迭代器，通过OrderedMap.Iterator创建。
+//
+// 新建的迭代器不指向任何元素，需要先调用First、Last或者Seek系列方法定位。
+// 修改map之后，已有的迭代器失效，需要重新定位。
+type Iterator[K, V any] struct {
+	m *OrderedMap[K, V]
+	// 从根节点到当前节点的路径，为空表示迭代器无效
+	stack []*node[K, V]
+}
+
+// Iterator 返回m上的一个未定位的迭代器。
+func (m *OrderedMap[K, V]) Iterator() *Iterator[K, V] {
+	return &Iterator[K, V]{m: m}
+}
+
+// Valid 返回迭代器是否指向某个元素。
+func (it *Iterator[K, V]) Valid() bool {
+	return len(it.stack) > 0
+}
+
+// Key 返回当前元素的key，迭代器无效时返回零值。
+func (it *Iterator[K, V]) Key() (key K) {
+	if n := it.current(); n != nil {
+		key = n.key
+	}
+	return key
+}
+
+// Value 返回当前元素的value，迭代器无效时返回零值。
+func (it *Iterator[K, V]) Value() (value V) {
+	if n := it.current(); n != nil {
+		value = n.value
+	}
+	return value
+}
+
+// First 定位到最小的key，map为空时返回false。
+func (it *Iterator[K, V]) First() bool {
+	it.stack = it.stack[:0]
+	it.pushLeft(it.m.root)
+	return it.Valid()
+}
+
+// Last 定位到最大的key，map为空时返回false。
+func (it *Iterator[K, V]) Last() bool {
+	it.stack = it.stack[:0]
+	it.pushRight(it.m.root)
+	return it.Valid()
+}
+
+// Seek 定位到与key相等的元素；不存在时迭代器无效，并返回false。
+func (it *Iterator[K, V]) Seek(key K) bool {
+	it.stack = it.stack[:0]
+	for root := it.m.root; root != nil; {
+		it.stack = append(it.stack, root)
+		c := it.m.compare(key, root.key)
+		if c == 0 {
+			return true
+		}
+		if c < 0 {
+			root = root.left
+		} else {
+			root = root.right
+		}
+	}
+	it.stack = it.stack[:0]
+	return false
+}
+
+// SeekGE 定位到大于等于key的最小元素；不存在时迭代器无效，并返回false。
+func (it *Iterator[K, V]) SeekGE(key K) bool {
+	it.stack = it.stack[:0]
+	depth := 0
+	for root := it.m.root; root != nil; {
+		it.stack = append(it.stack, root)
+		c := it.m.compare(key, root.key)
+		if c <= 0 {
+			depth = len(it.stack)
+			if c == 0 {
+				break
+			}
+			root = root.left
+		} else {
+			root = root.right
+		}
+	}
+	it.stack = it.stack[:depth]
+	return it.Valid()
+}
+
+// SeekLE 定位到小于等于key的最大元素；不存在时迭代器无效，并返回false。
+func (it *Iterator[K, V]) SeekLE(key K) bool {
+	it.stack = it.stack[:0]
+	depth := 0
+	for root := it.m.root; root != nil; {
+		it.stack = append(it.stack, root)
+		c := it.m.compare(key, root.key)
+		if c >= 0 {
+			depth = len(it.stack)
+			if c == 0 {
+				break
+			}
+			root = root.right
+		} else {
+			root = root.left
+		}
+	}
+	it.stack = it.stack[:depth]
+	return it.Valid()
+}
+
+// Next 移动到下一个（更大的）元素，越过最大的key之后迭代器无效，并返回false。
+func (it *Iterator[K, V]) Next() bool {
+	n := it.current()
+	if n == nil {
+		return false
+	}
+	if n.right != nil {
+		it.pushLeft(n.right)
+		return true
+	}
+	// 向上回溯，直到从左子树返回
+	for it.stack = it.stack[:len(it.stack)-1]; len(it.stack) > 0; it.stack = it.stack[:len(it.stack)-1] {
+		parent := it.current()
+		if parent.left == n {
+			return true
+		}
+		n = parent
+	}
+	return false
+}
+
+// Prev 移动到上一个（更小的）元素，越过最小的key之后迭代器无效，并返回false。
+func (it *Iterator[K, V]) Prev() bool {
+	n := it.current()
+	if n == nil {
+		return false
+	}
+	if n.left != nil {
+		it.pushRight(n.left)
+		return true
+	}
+	// 向上回溯，直到从右子树返回
+	for it.stack = it.stack[:len(it.stack)-1]; len(it.stack) > 0; it.stack = it.stack[:len(it.stack)-1] {
+		parent := it.current()
+		if parent.right == n {
+			return true
+		}
+		n = parent
+	}
+	return false
+}
+
+// current 返回当前节点，迭代器无效时返回nil。
+func (it *Iterator[K, V]) current() *node[K, V] {
+	if len(it.stack) == 0 {
+		return nil
+	}
+	return it.stack[len(it.stack)-1]
+}
+
+// pushLeft 把root及其左侧链上的节点依次压栈，栈顶为root子树中最小的节点。
+func (it *Iterator[K, V]) pushLeft(root *node[K, V]) {
+	for ; root != nil; root = root.left {
+		it.stack = append(it.stack, root)
+	}
+}
+
+// pushRight 把root及其右侧链上的节点依次压栈，栈顶为root子树中最大的节点。
+func (it *Iterator[K, V]) pushRight(root *node[K, V]) {
+	for ; root != nil; root = root.right {
+		it.stack = append(it.stack, root)
+	}
+}
+
+// All 返回按key升序遍历所有元素的迭代器，可以用于for range。
+func (m *OrderedMap[K, V]) All() iter.Seq2[K, V] {
+	return func(yield func(K, V) bool) {
+		it := m.Iterator()
+		for ok := it.First(); ok && yield(it.Key(), it.Value()); ok = it.Next() {
+		}
+	}
+}
+
+// Backward 返回按key降序遍历所有元素的迭代器。
+func (m *OrderedMap[K, V]) Backward() iter.Seq2[K, V] {
+	return func(yield func(K, V) bool) {
+		it := m.Iterator()
+		for ok := it.Last(); ok && yield(it.Key(), it.Value()); ok = it.Prev() {
+		}
+	}
+}
+
+// From 返回从大于等于key的最小元素开始，按key升序遍历的迭代器。
+func (m *OrderedMap[K, V]) From(key K) iter.Seq2[K, V] {
+	return func(yield func(K, V) bool) {
+		it := m.Iterator()
+		for ok := it.SeekGE(key); ok && yield(it.Key(), it.Value()); ok = it.Next() {
+		}
+	}
+}
@@ -0,0 +1,142 @@
+package omap_test
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/alex023/basekit/container/omap"
+)
+
+func ExampleOrderedMap_From() {
+	m := omap.NewOrdered[int, string]()
+	for i, name := range []string{"zero", "one", "two", "three", "four"} {
+		m.Insert(i*10, name)
+	}
+	for k, v := range m.From(15) {
+		if k > 30 {
+			break
+		}
+		fmt.Println(k, v)
+	}
+
+	// Output:
+	// 20 two
+	// 30 three
+}
+
+// evenMap 返回key为0, 2, ..., 2*(n-1)的OrderedMap。
+func evenMap(n int) *omap.OrderedMap[int, int] {
+	m := omap.NewOrdered[int, int]()
+	for i := n - 1; i >= 0; i-- {
+		m.Insert(2*i, i)
+	}
+	return m
+}
+
+func TestIteratorForwardBackward(t *testing.T) {
+	const n = 1000
+	m := evenMap(n)
+	it := m.Iterator()
+	if it.Valid() {
+		t.Fatal("new iterator should be invalid")
+	}
+	count := 0
+	for ok := it.First(); ok; ok = it.Next() {
+		if it.Key() != 2*count || it.Value() != count {
+			t.Fatalf("got %d=%d at %d", it.Key(), it.Value(), count)
+		}
+		count++
+	}
+	if count != n || it.Valid() {
+		t.Fatalf("visited %d, valid %v", count, it.Valid())
+	}
+	for ok := it.Last(); ok; ok = it.Prev() {
+		count--
+		if it.Key() != 2*count {
+			t.Fatalf("got %d at %d", it.Key(), count)
+		}
+	}
+	if count != 0 {
+		t.Fatalf("%d elements not visited backwards", count)
+	}
+}
+
+func TestIteratorSeek(t *testing.T) {
+	m := evenMap(100)
+	it := m.Iterator()
+	if !it.Seek(42) || it.Key() != 42 {
+		t.Errorf("Seek(42) failed")
+	}
+	if it.Seek(43) || it.Valid() {
+		t.Errorf("Seek(43) should fail")
+	}
+	tests := []struct {
+		key    int
+		ge, le int // -1表示不存在
+	}{
+		{-5, 0, -1},
+		{0, 0, 0},
+		{43, 44, 42},
+		{198, 198, 198},
+		{199, -1, 198},
+	}
+	for _, tt := range tests {
+		if ok := it.SeekGE(tt.key); ok != (tt.ge >= 0) || ok && it.Key() != tt.ge {
+			t.Errorf("SeekGE(%d) = %v, %d", tt.key, ok, it.Key())
+		}
+		if ok := it.SeekLE(tt.key); ok != (tt.le >= 0) || ok && it.Key() != tt.le {
+			t.Errorf("SeekLE(%d) = %v, %d", tt.key, ok, it.Key())
+		}
+	}
+	// 定位之后可以双向移动
+	it.SeekGE(43)
+	if !it.Prev() || it.Key() != 42 || !it.Next() || !it.Next() || it.Key() != 46 {
+		t.Errorf("unexpected position %d", it.Key())
+	}
+}
+
+func TestSeq(t *testing.T) {
+	m := evenMap(5)
+	var keys []int
+	for k := range m.All() {
+		keys = append(keys, k)
+	}
+	if want := []int{0, 2, 4, 6, 8}; !reflect.DeepEqual(keys, want) {
+		t.Errorf("All: %v, should be %v", keys, want)
+	}
+	keys = keys[:0]
+	for k := range m.Backward() {
+		if k < 4 {
+			break
+		}
+		keys = append(keys, k)
+	}
+	if want := []int{8, 6, 4}; !reflect.DeepEqual(keys, want) {
+		t.Errorf("Backward: %v, should be %v", keys, want)
+	}
+	keys = keys[:0]
+	for k := range m.From(3) {
+		keys = append(keys, k)
+	}
+	if want := []int{4, 6, 8}; !reflect.DeepEqual(keys, want) {
+		t.Errorf("From: %v, should be %v", keys, want)
+	}
+	for range omap.NewOrdered[int, int]().All() {
+		t.Error("empty map should yield nothing")
+	}
+}
+
+func TestMapIterator(t *testing.T) {
+	m := omap.NewIntKeyed()
+	for _, n := range []int{3, 1, 2} {
+		m.Insert(n, n*10)
+	}
+	var values []interface{}
+	for _, v := range m.Backward() {
+		values = append(values, v)
+	}
+	if want := []interface{}{30, 20, 10}; !reflect.DeepEqual(values, want) {
+		t.Errorf("Backward: %v, should be %v", values, want)
+	}
+}
@@ -21,47 +21,51 @@
 
 // Package omap 通过红黑树实现了高效率的有序map。
 //
-// Keys 与 values 可以是任意类型,但key必须要有支持的less比较 方法。
+// OrderedMap[K, V] 是类型安全的泛型实现：key满足cmp.Ordered时通过NewOrdered创建，
+// 否则通过NewOrderedFunc提供比较函数。
+// Map 是基于interface{}的旧API，Keys 与 values 可以是任意类型,但key必须要有支持的less比较 方法。
 // 在通过调用New方法的时候,需要提供该方法。
 package omap
 
 import (
+	"cmp"
 	"strings"
+	"sync/atomic"
 )
 
 // NewStringKeyed 返回一个初始化完成的空有序Map,其key是大小写敏感的string。
 func NewStringKeyed() *Map {
-	return &Map{less: func(a, b interface{}) bool {
+	return New(func(a, b interface{}) bool {
 		return a.(string) < b.(string)
-	}}
+	})
 }
 
 // NewCaseFoldedKeyed 返回一个初始化完成的空有序Map,其key是大小写不敏感的string。
 func NewCaseFoldedKeyed() *Map {
-	return &Map{less: func(a, b interface{}) bool {
+	return New(func(a, b interface{}) bool {
 		return strings.ToLower(a.(string)) < strings.ToLower(b.(string))
-	}}
+	})
 }
 
 // NewIntKeyed 返回一个初始化的空有序Map,其key按照int类型识别。
 func NewIntKeyed() *Map {
-	return &Map{less: func(a, b interface{}) bool {
+	return New(func(a, b interface{}) bool {
 		return a.(int) < b.(int)
-	}}
+	})
 }
 
 // NewInt64Keyed 返回一个初始化的空有序Map,其key按照int64类型识别。
 func NewInt64Keyed() *Map {
-	return &Map{less: func(a, b interface{}) bool {
+	return New(func(a, b interface{}) bool {
 		return a.(int64) < b.(int64)
-	}}
+	})
 }
 
 // NewFloat64Keyed 返回一个初始化的空有序Map,其key按照按照float64类型识别。
 func NewFloat64Keyed() *Map {
-	return &Map{less: func(a, b interface{}) bool {
+	return New(func(a, b interface{}) bool {
 		return a.(float64) < b.(float64)
-	}}
+	})
 }
 
 // New 返回一个空的有序Map,其比较函数func(interface{}, interface{}) bool,需要用户自己识别。
@@ -75,57 +79,91 @@ func NewFloat64Keyed() *Map {
 //              return α.Y < β.Y
 //          })
 func New(less func(interface{}, interface{}) bool) *Map {
-	return &Map{less: less}
+	return &Map{OrderedMap[interface{}, interface{}]{compare: func(a, b interface{}) int {
+		if less(a, b) {
+			return -1
+		}
+		if less(b, a) {
+			return 1
+		}
+		return 0
+	}}}
+}
+
+// NewOrdered 返回一个空的有序OrderedMap,其key按照cmp.Compare的顺序排列。
+func NewOrdered[K cmp.Ordered, V any]() *OrderedMap[K, V] {
+	return &OrderedMap[K, V]{compare: cmp.Compare[K]}
 }
 
-// Map 是一个key有序map。
+// NewOrderedFunc 返回一个空的有序OrderedMap,key通过compare比较:
+// a小于、等于、大于b时,compare(a, b)分别返回负数、0、正数。比如:
+//      pointMap := omap.NewOrderedFunc[Point, string](func(a, b Point) int {
+//              if c := cmp.Compare(a.X, b.X); c != 0 {
+//                  return c
+//              }
+//              return cmp.Compare(a.Y, b.Y)
+//          })
+func NewOrderedFunc[K, V any](compare func(a, b K) int) *OrderedMap[K, V] {
+	return &OrderedMap[K, V]{compare: compare}
+}
+
+// Map 是一个key有序map,key与value均为interface{}。
 // The zero value is an invalid map! 通过包中提供的构造函数,比如New()等,创建特殊“键-值”类别的map。
 type Map struct {
-	root   *node
-	less   func(interface{}, interface{}) bool
-	length int
+	OrderedMap[interface{}, interface{}]
+}
+
+// OrderedMap 是一个key有序map,key的类型为K,value的类型为V。
+// The zero value is an invalid map! 通过NewOrdered或NewOrderedFunc创建。
+// 唯一的例外是解码：K为预定义的整数、浮点数或string类型时，零值的map可以直接作为
+// UnmarshalJSON、UnmarshalBinary与GobDecode的目标，并使用cmp.Compare[K]；其他类型返回ErrNoCompare。
+type OrderedMap[K, V any] struct {
+	root    *node[K, V]
+	compare func(a, b K) int
+	length  int
+
+	keyCodec   Codec[K] // 为nil时使用默认的编码，见SetCodec
+	valueCodec Codec[V]
 }
 
-type node struct {
-	key, value  interface{}
+type node[K, V any] struct {
+	key         K
+	value       V
 	red         bool
-	left, right *node
+	size        int    // 以该节点为根的子树中的节点数
+	gen         uint64 // 创建该节点的修改操作，见txn
+	left, right *node[K, V]
 }
 
 // Insert inserts a new key-value into the Map and returns true; or
 // replaces an existing key-value pair's value if the keys are equal and
 // returns false. For example:
 //      inserted := myMap.Insert(key, value).
-func (m *Map) Insert(key, value interface{}) (inserted bool) {
-	m.root, inserted = m.insert(m.root, key, value)
-	m.root.red = false
-	if inserted {
-		m.length++
-	}
-	return inserted
+func (m *OrderedMap[K, V]) Insert(key K, value V) (inserted bool) {
+	return m.insertWith(m.txn(), key, value)
 }
 
 // Find returns the value and true if the key is in the Map or nil and
 // false otherwise. For example:
 //      value, found := myMap.Find(key).
-func (m *Map) Find(key interface{}) (value interface{}, found bool) {
+func (m *OrderedMap[K, V]) Find(key K) (value V, found bool) {
 	root := m.root
 	for root != nil {
-		if m.less(key, root.key) {
+		if c := m.compare(key, root.key); c < 0 {
 			root = root.left
-		} else if m.less(root.key, key) {
+		} else if c > 0 {
 			root = root.right
 		} else {
 			return root.value, true
 		}
 	}
-	return nil, false
+	return value, false
 }
 
 // First 返回有序Map中,最左上角叶子节点的KV组。
-func (m *Map) First() (key, value interface{}, found bool) {
+func (m *OrderedMap[K, V]) First() (key K, value V, found bool) {
 	if m.root == nil {
-		return nil, nil, false
+		return key, value, false
 	}
 	root := m.root
 	for root.left != nil {
@@ -138,9 +176,9 @@ func (m *Map) First() (key, value interface{}, found bool) {
 }
 
 // Latest 返回有序Map中,最右上角叶子节点的KV组。
-func (m *Map) Latest() (key, value interface{}, found bool) {
+func (m *OrderedMap[K, V]) Latest() (key K, value V, found bool) {
 	if m.root == nil {
-		return nil, nil, false
+		return key, value, false
 	}
 	root := m.root
 	for root.right != nil {
@@ -157,9 +195,34 @@ func (m *Map) Latest() (key, value interface{}, found bool) {
 // true, or does nothing and returns false if there is no key-value with
 // the given key. For example:
 //      deleted := myMap.Delete(key).
-func (m *Map) Delete(key interface{}) (deleted bool) {
+func (m *OrderedMap[K, V]) Delete(key K) (deleted bool) {
+	return m.deleteWith(m.txn(), key)
+}
+
+// Do 调用给定的func,有序将key-value作为输入参数执行。
+func (m *OrderedMap[K, V]) Do(function func(K, V)) {
+	do(m.root, function)
+}
+
+// Len 返回map中的键值对数量
+func (m *OrderedMap[K, V]) Len() int {
+	return m.length
+}
+
+// insertWith 通过t执行Insert。
+func (m *OrderedMap[K, V]) insertWith(t txn[K, V], key K, value V) (inserted bool) {
+	m.root, inserted = t.insert(m.root, key, value)
+	m.root.red = false
+	if inserted {
+		m.length++
+	}
+	return inserted
+}
+
+// deleteWith 通过t执行Delete。
+func (m *OrderedMap[K, V]) deleteWith(t txn[K, V], key K) (deleted bool) {
 	if m.root != nil {
-		if m.root, deleted = m.remove(m.root, key); m.root != nil {
+		if m.root, deleted = t.remove(m.root, key); m.root != nil {
 			m.root.red = false
 		}
 	}
@@ -169,43 +232,78 @@ func (m *Map) Delete(key interface{}) (deleted bool) {
 	return deleted
 }
 
-// Do 调用给定的func,有序将key-value作为输入参数执行。
-func (m *Map) Do(function func(interface{}, interface{})) {
-	do(m.root, function)
+// txn 返回原地修改m的上下文。
+func (m *OrderedMap[K, V]) txn() txn[K, V] {
+	return txn[K, V]{compare: m.compare}
 }
 
-// Len 返回map中的键值对数量
-func (m *Map) Len() int {
-	return m.length
+// txn 是一次修改操作的上下文。gen为0时原地修改节点；否则采用路径复制：
+// 修改不是本次操作创建的节点（gen不同）之前，先复制该节点，原有的树保持不变。
+//
+// 下面的红黑树算法都通过txn执行，修改节点之前要先通过own取得该节点。
+type txn[K, V any] struct {
+	compare func(a, b K) int
+	gen     uint64
 }
 
-func (m *Map) insert(root *node, key, value interface{}) (*node, bool) {
+// generation 为每次路径复制的修改操作分配不同的gen。
+var generation atomic.Uint64
+
+// own 返回可以修改的root：路径复制时，如果root不是本次操作创建的，则返回它的副本。
+func (t txn[K, V]) own(root *node[K, V]) *node[K, V] {
+	if t.gen == 0 || root == nil || root.gen == t.gen {
+		return root
+	}
+	c := *root
+	c.gen = t.gen
+	return &c
+}
+
+func (t txn[K, V]) insert(root *node[K, V], key K, value V) (*node[K, V], bool) {
 	inserted := false
 	if root == nil { // If the key was in the tree it would belong here
-		return &node{key: key, value: value, red: true}, true
+		return &node[K, V]{key: key, value: value, red: true, size: 1, gen: t.gen}, true
 	}
-	if isRed(root.left) && isRed(root.right) {
-		colorFlip(root)
-	}
-	if m.less(key, root.key) {
-		root.left, inserted = m.insert(root.left, key, value)
-	} else if m.less(root.key, key) {
-		root.right, inserted = m.insert(root.right, key, value)
+	root = t.own(root)
+	if c := t.compare(key, root.key); c < 0 {
+		root.left, inserted = t.insert(root.left, key, value)
+	} else if c > 0 {
+		root.right, inserted = t.insert(root.right, key, value)
 	} else { // The key is already in the tree so just replace its value
 		root.value = value
 	}
+	root.resize()
 	if isRed(root.right) && !isRed(root.left) {
-		root = rotateLeft(root)
+		root = t.rotateLeft(root)
 	}
 	if isRed(root.left) && isRed(root.left.left) {
-		root = rotateRight(root)
+		root = t.rotateRight(root)
+	}
+	// 按照2-3树的方式在回溯时分裂4-节点：remove的实现依赖于此，
+	// 在下行时分裂（2-3-4树）会导致删除时丢失节点。
+	if isRed(root.left) && isRed(root.right) {
+		t.colorFlip(root)
 	}
 	return root, inserted
 }
 
-func isRed(root *node) bool { return root != nil && root.red }
+func isRed[K, V any](root *node[K, V]) bool { return root != nil && root.red }
+
+func sizeOf[K, V any](root *node[K, V]) int {
+	if root == nil {
+		return 0
+	}
+	return root.size
+}
+
+// resize 根据左右子树重新计算节点数。
+func (root *node[K, V]) resize() {
+	root.size = 1 + sizeOf(root.left) + sizeOf(root.right)
+}
 
-func colorFlip(root *node) {
+// colorFlip 翻转root及其子节点的颜色，root必须是已经通过own取得的节点。
+func (t txn[K, V]) colorFlip(root *node[K, V]) {
+	root.left, root.right = t.own(root.left), t.own(root.right)
 	root.red = !root.red
 	if root.left != nil {
 		root.left.red = !root.left.red
@@ -215,7 +313,7 @@ func colorFlip(root *node) {
 	}
 }
 
-func rotateLeft(root *node) *node {
+func (t txn[K, V]) rotateLeft(root *node[K, V]) *node[K, V] {
 	//
 	// The illation of left rotation
 	//
@@ -229,15 +327,18 @@ func rotateLeft(root *node) *node {
 	// It should be note that during the rotating we do not change
 	// the Nodes' color.
 	//
-	x := root.right
+	root = t.own(root)
+	x := t.own(root.right)
 	root.right = x.left
 	x.left = root
 	x.red = root.red
 	root.red = true
+	root.resize()
+	x.resize()
 	return x
 }
 
-func rotateRight(root *node) *node {
+func (t txn[K, V]) rotateRight(root *node[K, V]) *node[K, V] {
 	//
 	// The illation of right rotation
 	//
@@ -251,15 +352,18 @@ func rotateRight(root *node) *node {
 	// It should be note that during the rotating we do not change
 	// the Nodes' color.
 	//
-	x := root.left
+	root = t.own(root)
+	x := t.own(root.left)
 	root.left = x.right
 	x.right = root
 	x.red = root.red
 	root.red = true
+	root.resize()
+	x.resize()
 	return x
 }
 
-func do(root *node, function func(interface{}, interface{})) {
+func do[K, V any](root *node[K, V], function func(K, V)) {
 	if root != nil {
 		do(root.left, function)
 		function(root.key, root.value)
@@ -269,87 +373,89 @@ func do(root *node, function func(interface{}, interface{})) {
 
 // We do not provide an exported First() method because this is an
 // implementation detail.
-func first(root *node) *node {
+func first[K, V any](root *node[K, V]) *node[K, V] {
 	for root.left != nil {
 		root = root.left
 	}
 	return root
 }
 
-func (m *Map) remove(root *node, key interface{}) (*node, bool) {
+func (t txn[K, V]) remove(root *node[K, V], key K) (*node[K, V], bool) {
 	deleted := false
-	if m.less(key, root.key) {
+	root = t.own(root)
+	if t.compare(key, root.key) < 0 {
 		if root.left != nil {
 			if !isRed(root.left) && !isRed(root.left.left) {
-				root = moveRedLeft(root)
+				root = t.moveRedLeft(root)
 			}
-			root.left, deleted = m.remove(root.left, key)
+			root.left, deleted = t.remove(root.left, key)
 		}
 	} else {
 		if isRed(root.left) {
-			root = rotateRight(root)
+			root = t.rotateRight(root)
 		}
-		if !m.less(key, root.key) && !m.less(root.key, key) &&
-			root.right == nil {
+		if t.compare(key, root.key) == 0 && root.right == nil {
 			return nil, true
 		}
 		if root.right != nil {
 			if !isRed(root.right) && !isRed(root.right.left) {
-				root = moveRedRight(root)
+				root = t.moveRedRight(root)
 			}
-			if !m.less(key, root.key) && !m.less(root.key, key) {
+			if t.compare(key, root.key) == 0 {
 				smallest := first(root.right)
 				root.key = smallest.key
 				root.value = smallest.value
-				root.right = deleteMinimum(root.right)
+				root.right = t.deleteMinimum(root.right)
 				deleted = true
 			} else {
-				root.right, deleted = m.remove(root.right, key)
+				root.right, deleted = t.remove(root.right, key)
 			}
 		}
 	}
-	return fixUp(root), deleted
+	return t.fixUp(root), deleted
 }
 
-func moveRedLeft(root *node) *node {
-	colorFlip(root)
+func (t txn[K, V]) moveRedLeft(root *node[K, V]) *node[K, V] {
+	t.colorFlip(root)
 	if root.right != nil && isRed(root.right.left) {
-		root.right = rotateRight(root.right)
-		root = rotateLeft(root)
-		colorFlip(root)
+		root.right = t.rotateRight(root.right)
+		root = t.rotateLeft(root)
+		t.colorFlip(root)
 	}
 	return root
 }
 
-func moveRedRight(root *node) *node {
-	colorFlip(root)
+func (t txn[K, V]) moveRedRight(root *node[K, V]) *node[K, V] {
+	t.colorFlip(root)
 	if root.left != nil && isRed(root.left.left) {
-		root = rotateRight(root)
-		colorFlip(root)
+		root = t.rotateRight(root)
+		t.colorFlip(root)
 	}
 	return root
 }
 
-func deleteMinimum(root *node) *node {
+func (t txn[K, V]) deleteMinimum(root *node[K, V]) *node[K, V] {
 	if root.left == nil {
 		return nil
 	}
+	root = t.own(root)
 	if !isRed(root.left) && !isRed(root.left.left) {
-		root = moveRedLeft(root)
+		root = t.moveRedLeft(root)
 	}
-	root.left = deleteMinimum(root.left)
-	return fixUp(root)
+	root.left = t.deleteMinimum(root.left)
+	return t.fixUp(root)
 }
 
-func fixUp(root *node) *node {
+func (t txn[K, V]) fixUp(root *node[K, V]) *node[K, V] {
+	root.resize()
 	if isRed(root.right) {
-		root = rotateLeft(root)
+		root = t.rotateLeft(root)
 	}
 	if isRed(root.left) && isRed(root.left.left) {
-		root = rotateRight(root)
+		root = t.rotateRight(root)
 	}
 	if isRed(root.left) && isRed(root.right) {
-		colorFlip(root)
+		t.colorFlip(root)
 	}
 	return root
 }
@@ -197,9 +197,8 @@ func TestOMap_FindStructKey(t *testing.T) {
 	}
 }
 
-// TODO:this test cannot pass,bacause some delete will cause other key change the same
+// 测试逐个查找并删除随机结构体key
 func TestOMap_DelStructKey(t *testing.T) {
-	t.Skip()
 	var (
 		size = 1000
 	)
@@ -0,0 +1,110 @@
+package omap_test
+
+import (
+	"cmp"
+	"math/rand"
+	"sort"
+	"testing"
+
+	"github.com/alex023/basekit/container/omap"
+)
+
+// checkOrdered 验证OrderedMap与内置map的内容一致，并且按key升序遍历。
+func checkOrdered(t *testing.T, m *omap.OrderedMap[int, int], want map[int]int) {
+	t.Helper()
+	if m.Len() != len(want) {
+		t.Fatalf("map len %d should be %d", m.Len(), len(want))
+	}
+	keys := make([]int, 0, len(want))
+	for k := range want {
+		keys = append(keys, k)
+	}
+	sort.Ints(keys)
+	i := 0
+	m.Do(func(k, v int) {
+		if i >= len(keys) || k != keys[i] || v != want[k] {
+			t.Fatalf("unexpected pair %d=%d at %d", k, v, i)
+		}
+		i++
+	})
+	if i != len(keys) {
+		t.Fatalf("visited %d pairs, should be %d", i, len(keys))
+	}
+}
+
+func TestOrderedMapRandom(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	m := omap.NewOrdered[int, int]()
+	want := make(map[int]int)
+	for i := 0; i < 20000; i++ {
+		k := r.Intn(1000)
+		if r.Intn(3) == 0 {
+			_, ok := want[k]
+			if deleted := m.Delete(k); deleted != ok {
+				t.Fatalf("Delete(%d) = %v, should be %v", k, deleted, ok)
+			}
+			delete(want, k)
+			continue
+		}
+		_, ok := want[k]
+		if inserted := m.Insert(k, i); inserted == ok {
+			t.Fatalf("Insert(%d) = %v, should be %v", k, inserted, !ok)
+		}
+		want[k] = i
+	}
+	checkOrdered(t, m, want)
+	for k, v := range want {
+		if value, found := m.Find(k); !found || value != v {
+			t.Fatalf("Find(%d) = %d, %v, should be %d", k, value, found, v)
+		}
+	}
+}
+
+func TestOrderedMapFunc(t *testing.T) {
+	type point struct{ X, Y int }
+	m := omap.NewOrderedFunc[point, string](func(a, b point) int {
+		if c := cmp.Compare(a.X, b.X); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.Y, b.Y)
+	})
+	m.Insert(point{2, 1}, "c")
+	m.Insert(point{1, 2}, "b")
+	m.Insert(point{1, 1}, "a")
+	var order string
+	m.Do(func(_ point, v string) { order += v })
+	if order != "abc" {
+		t.Errorf("order %q should be %q", order, "abc")
+	}
+	if k, v, ok := m.Latest(); !ok || k != (point{2, 1}) || v != "c" {
+		t.Errorf("Latest() = %v, %q, %v", k, v, ok)
+	}
+}
+
+func TestOrderedMapEmpty(t *testing.T) {
+	m := omap.NewOrdered[string, int]()
+	if _, _, ok := m.First(); ok {
+		t.Error("First() on empty map should fail")
+	}
+	if _, _, ok := m.Latest(); ok {
+		t.Error("Latest() on empty map should fail")
+	}
+	if v, ok := m.Find("a"); ok || v != 0 {
+		t.Errorf("Find() on empty map = %d, %v", v, ok)
+	}
+	if m.Delete("a") {
+		t.Error("Delete() on empty map should fail")
+	}
+}
+
+func BenchmarkOrderedMapFindSuccess(b *testing.B) {
+	b.StopTimer()
+	m := omap.NewOrdered[int, int]()
+	for i := 0; i < 1e6; i++ {
+		m.Insert(i, i)
+	}
+	b.StartTimer()
+	for i := 0; i < b.N; i++ {
+		m.Find(i % 1e6)
+	}
+}
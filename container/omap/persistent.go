@@ -0,0 +1,157 @@
+package omap
+
+import (
+	"cmp"
+	"iter"
+)
+
+// PersistentMap 是不可变的有序map：Insert、Delete等修改操作不改变原有的map，
+// 而是返回一个新的map。新旧map共享未修改的节点（路径复制），每次修改只分配O(log n)个节点。
+//
+// 由于从不修改，PersistentMap可以不加锁地同时交给多个goroutine读取，适合作为配置表等数据的快照。
+//
+// The zero value is an invalid map! 通过NewPersistent或NewPersistentFunc创建。
+type PersistentMap[K, V any] struct {
+	tree OrderedMap[K, V]
+}
+
+// NewPersistent 返回一个空的PersistentMap,其key按照cmp.Compare的顺序排列。
+func NewPersistent[K cmp.Ordered, V any]() *PersistentMap[K, V] {
+	return NewPersistentFunc[K, V](cmp.Compare[K])
+}
+
+// NewPersistentFunc 返回一个空的PersistentMap,key通过compare比较，含义与NewOrderedFunc相同。
+func NewPersistentFunc[K, V any](compare func(a, b K) int) *PersistentMap[K, V] {
+	return &PersistentMap[K, V]{tree: OrderedMap[K, V]{compare: compare}}
+}
+
+// Snapshot 返回当前版本的只读快照，复杂度为O(1)。
+func (m *ConcurrentMap[K, V]) Snapshot() *PersistentMap[K, V] {
+	return &PersistentMap[K, V]{tree: *m.current.Load()}
+}
+
+// txn 返回基于p做路径复制的修改上下文。
+func (p *PersistentMap[K, V]) txn() txn[K, V] {
+	return txn[K, V]{compare: p.tree.compare, gen: generation.Add(1)}
+}
+
+// Insert 返回插入或者替换了key对应value之后的新map。
+func (p *PersistentMap[K, V]) Insert(key K, value V) *PersistentMap[K, V] {
+	next := &PersistentMap[K, V]{tree: p.tree}
+	next.tree.insertWith(p.txn(), key, value)
+	return next
+}
+
+// Delete 返回删除了key之后的新map；key不存在时返回p本身。
+func (p *PersistentMap[K, V]) Delete(key K) *PersistentMap[K, V] {
+	if _, found := p.tree.Find(key); !found {
+		return p
+	}
+	next := &PersistentMap[K, V]{tree: p.tree}
+	next.tree.deleteWith(p.txn(), key)
+	return next
+}
+
+// DeleteRange 返回删除了区间内所有元素之后的新map，区间的含义与Range相同；
+// 区间内没有元素时返回p本身。
+func (p *PersistentMap[K, V]) DeleteRange(lo, hi K, opts ...RangeOption) *PersistentMap[K, V] {
+	keys := make([]K, 0, p.tree.CountRange(lo, hi, opts...))
+	p.tree.Range(lo, hi, func(key K, _ V) bool {
+		keys = append(keys, key)
+		return true
+	}, opts...)
+	if len(keys) == 0 {
+		return p
+	}
+	next := &PersistentMap[K, V]{tree: p.tree}
+	t := p.txn()
+	for _, key := range keys {
+		next.tree.deleteWith(t, key)
+	}
+	return next
+}
+
+// Find 返回key对应的value。
+func (p *PersistentMap[K, V]) Find(key K) (value V, found bool) {
+	return p.tree.Find(key)
+}
+
+// First 返回key最小的元素。
+func (p *PersistentMap[K, V]) First() (key K, value V, found bool) {
+	return p.tree.First()
+}
+
+// Latest 返回key最大的元素。
+func (p *PersistentMap[K, V]) Latest() (key K, value V, found bool) {
+	return p.tree.Latest()
+}
+
+// Floor 返回小于等于key的最大元素。
+func (p *PersistentMap[K, V]) Floor(key K) (k K, value V, found bool) {
+	return p.tree.Floor(key)
+}
+
+// Ceiling 返回大于等于key的最小元素。
+func (p *PersistentMap[K, V]) Ceiling(key K) (k K, value V, found bool) {
+	return p.tree.Ceiling(key)
+}
+
+// Lower 返回严格小于key的最大元素。
+func (p *PersistentMap[K, V]) Lower(key K) (k K, value V, found bool) {
+	return p.tree.Lower(key)
+}
+
+// Higher 返回严格大于key的最小元素。
+func (p *PersistentMap[K, V]) Higher(key K) (k K, value V, found bool) {
+	return p.tree.Higher(key)
+}
+
+// Len 返回map中的键值对数量。
+func (p *PersistentMap[K, V]) Len() int {
+	return p.tree.Len()
+}
+
+// Rank 返回map中小于key的元素数量。
+func (p *PersistentMap[K, V]) Rank(key K) int {
+	return p.tree.Rank(key)
+}
+
+// Select 返回按升序排列的第k个元素（从0开始）。
+func (p *PersistentMap[K, V]) Select(k int) (key K, value V, found bool) {
+	return p.tree.Select(k)
+}
+
+// CountRange 返回区间内的元素数量。
+func (p *PersistentMap[K, V]) CountRange(lo, hi K, opts ...RangeOption) int {
+	return p.tree.CountRange(lo, hi, opts...)
+}
+
+// Do 按key升序对每个元素调用function。
+func (p *PersistentMap[K, V]) Do(function func(K, V)) {
+	p.tree.Do(function)
+}
+
+// Range 按顺序对区间内的每个元素调用fn，fn返回false时停止遍历。
+func (p *PersistentMap[K, V]) Range(lo, hi K, fn func(key K, value V) bool, opts ...RangeOption) {
+	p.tree.Range(lo, hi, fn, opts...)
+}
+
+// Iterator 返回一个未定位的迭代器。
+func (p *PersistentMap[K, V]) Iterator() *Iterator[K, V] {
+	return p.tree.Iterator()
+}
+
+// All 返回按key升序遍历的迭代器。
+func (p *PersistentMap[K, V]) All() iter.Seq2[K, V] {
+	return p.tree.All()
+}
+
+// Backward 返回按key降序遍历的迭代器。
+func (p *PersistentMap[K, V]) Backward() iter.Seq2[K, V] {
+	return p.tree.Backward()
+}
+
+// From 返回从大于等于key的最小元素开始按key升序遍历的迭代器。
+func (p *PersistentMap[K, V]) From(key K) iter.Seq2[K, V] {
+	return p.tree.From(key)
+}
@@ -0,0 +1,123 @@
+package omap_test
+
+import (
+	"math"
+	"math/rand"
+	"sort"
+	"testing"
+
+	"github.com/alex023/basekit/container/omap"
+)
+
+// checkPersistent 验证PersistentMap与内置map的内容一致，并且按key升序遍历。
+func checkPersistent(t *testing.T, p *omap.PersistentMap[int, int], want map[int]int) {
+	t.Helper()
+	if p.Len() != len(want) {
+		t.Fatalf("map len %d should be %d", p.Len(), len(want))
+	}
+	keys := make([]int, 0, len(want))
+	for k := range want {
+		keys = append(keys, k)
+	}
+	sort.Ints(keys)
+	i := 0
+	for k, v := range p.All() {
+		if k != keys[i] || v != want[k] {
+			t.Fatalf("unexpected pair %d=%d at %d", k, v, i)
+		}
+		i++
+	}
+}
+
+func TestPersistentMapVersions(t *testing.T) {
+	r := rand.New(rand.NewSource(3))
+	var (
+		versions []*omap.PersistentMap[int, int]
+		models   []map[int]int
+	)
+	p := omap.NewPersistent[int, int]()
+	model := make(map[int]int)
+	for i := 0; i < 2000; i++ {
+		k := r.Intn(300)
+		if r.Intn(3) == 0 {
+			p = p.Delete(k)
+			delete(model, k)
+		} else {
+			p = p.Insert(k, i)
+			model[k] = i
+		}
+		if i%100 == 0 {
+			snapshot := make(map[int]int, len(model))
+			for k, v := range model {
+				snapshot[k] = v
+			}
+			versions = append(versions, p)
+			models = append(models, snapshot)
+		}
+	}
+	// 之后的修改不影响之前的版本
+	for i, v := range versions {
+		checkPersistent(t, v, models[i])
+	}
+	checkPersistent(t, p, model)
+}
+
+func TestPersistentMapShared(t *testing.T) {
+	p := omap.NewPersistent[int, string]()
+	a := p.Insert(1, "a")
+	b := a.Insert(2, "b")
+	c := b.Insert(1, "c")
+	if p.Len() != 0 || a.Len() != 1 || b.Len() != 2 || c.Len() != 2 {
+		t.Fatalf("unexpected lengths %d %d %d %d", p.Len(), a.Len(), b.Len(), c.Len())
+	}
+	if v, _ := b.Find(1); v != "a" {
+		t.Errorf("b[1] = %q, should be %q", v, "a")
+	}
+	if v, _ := c.Find(1); v != "c" {
+		t.Errorf("c[1] = %q, should be %q", v, "c")
+	}
+	if d := c.Delete(3); d != c {
+		t.Error("deleting a missing key should return the same map")
+	}
+	if d := c.DeleteRange(0, 10); d.Len() != 0 || c.Len() != 2 {
+		t.Errorf("DeleteRange: %d, original %d", d.Len(), c.Len())
+	}
+}
+
+func TestPersistentMapAllocs(t *testing.T) {
+	const n = 1 << 12
+	p := omap.NewPersistent[int, int]()
+	for i := 0; i < n; i++ {
+		p = p.Insert(i, i)
+	}
+	// 红黑树的高度不超过2*log2(n)，路径复制与旋转只复制路径附近的节点
+	limit := 3*2*math.Log2(n) + 2
+	k := 0
+	if allocs := testing.AllocsPerRun(100, func() {
+		p.Insert(k*2+1, 0)
+		k++
+	}); allocs > limit {
+		t.Errorf("Insert allocated %v times, should be at most %v", allocs, limit)
+	}
+	k = 0
+	if allocs := testing.AllocsPerRun(100, func() {
+		p.Delete(k)
+		k++
+	}); allocs > limit {
+		t.Errorf("Delete allocated %v times, should be at most %v", allocs, limit)
+	}
+}
+
+func TestConcurrentMapSnapshotPersistent(t *testing.T) {
+	m := omap.NewConcurrent[string, int]()
+	m.Insert("a", 1)
+	snapshot := m.Snapshot()
+	m.Insert("b", 2)
+	m.Delete("a")
+	if v, ok := snapshot.Find("a"); !ok || v != 1 || snapshot.Len() != 1 {
+		t.Errorf("snapshot changed: %d, %v, len %d", v, ok, snapshot.Len())
+	}
+	if next := snapshot.Insert("c", 3); next.Len() != 2 || m.Len() != 1 {
+		t.Errorf("snapshot should be independent of the map")
+	}
+}
@@ -0,0 +1,97 @@
+package omap
+
+import "math/bits"
+
+// RangeOption 调整Range与DeleteRange的区间边界与遍历顺序，多个选项可以用|组合。
+// 默认的区间为[lo, hi)，按key升序遍历。
+type RangeOption int
+
+const (
+	// LoExclusive 表示区间不包含lo。
+	LoExclusive RangeOption = 1 << iota
+	// HiInclusive 表示区间包含hi。
+	HiInclusive
+	// Reverse 表示按key降序遍历。
+	Reverse
+)
+
+// Range 按顺序对区间内的每个元素调用fn，fn返回false时停止遍历。比如遍历[lo, hi]内的元素:
+//      m.Range(lo, hi, func(k int, v string) bool {
+//          fmt.Println(k, v)
+//          return true
+//      }, omap.HiInclusive)
+func (m *OrderedMap[K, V]) Range(lo, hi K, fn func(key K, value V) bool, opts ...RangeOption) {
+	opt := combine(opts)
+	it := m.Iterator()
+	if opt&Reverse == 0 {
+		for ok := it.seekLo(lo, opt); ok && it.belowHi(hi, opt) && fn(it.Key(), it.Value()); ok = it.Next() {
+		}
+		return
+	}
+	for ok := it.seekHi(hi, opt); ok && it.aboveLo(lo, opt) && fn(it.Key(), it.Value()); ok = it.Prev() {
+	}
+}
+
+// DeleteRange 删除区间内的所有元素，返回删除的数量。opts中的Reverse不影响结果。
+//
+// 删除的元素较少时逐个删除，否则用剩余的元素重建，复杂度为O(min(k log n, n))。
+func (m *OrderedMap[K, V]) DeleteRange(lo, hi K, opts ...RangeOption) int {
+	opt := combine(opts)
+	start, end := m.count(lo, opt&LoExclusive != 0), m.count(hi, opt&HiInclusive != 0)
+	if end <= start {
+		return 0
+	}
+	if (end-start)*bits.Len(uint(m.length)) > m.length {
+		entries := m.entries()
+		m.rebuild(append(entries[:start], entries[end:]...))
+		return end - start
+	}
+	keys := make([]K, 0, end-start)
+	m.Range(lo, hi, func(key K, _ V) bool {
+		keys = append(keys, key)
+		return true
+	}, opts...)
+	for _, key := range keys {
+		m.Delete(key)
+	}
+	return len(keys)
+}
+
+// combine 合并多个选项。
+func combine(opts []RangeOption) RangeOption {
+	var opt RangeOption
+	for _, o := range opts {
+		opt |= o
+	}
+	return opt
+}
+
+// seekLo 定位到区间下界之上的最小元素。
+func (it *Iterator[K, V]) seekLo(lo K, opt RangeOption) bool {
+	ok := it.SeekGE(lo)
+	if ok && opt&LoExclusive != 0 && it.m.compare(it.Key(), lo) == 0 {
+		ok = it.Next()
+	}
+	return ok
+}
+
+// seekHi 定位到区间上界之下的最大元素。
+func (it *Iterator[K, V]) seekHi(hi K, opt RangeOption) bool {
+	ok := it.SeekLE(hi)
+	if ok && opt&HiInclusive == 0 && it.m.compare(it.Key(), hi) == 0 {
+		ok = it.Prev()
+	}
+	return ok
+}
+
+// belowHi 返回当前元素是否没有超出区间上界。
+func (it *Iterator[K, V]) belowHi(hi K, opt RangeOption) bool {
+	c := it.m.compare(it.Key(), hi)
+	return c < 0 || c == 0 && opt&HiInclusive != 0
+}
+
+// aboveLo 返回当前元素是否没有超出区间下界。
+func (it *Iterator[K, V]) aboveLo(lo K, opt RangeOption) bool {
+	c := it.m.compare(it.Key(), lo)
+	return c > 0 || c == 0 && opt&LoExclusive == 0
+}
@@ -0,0 +1,81 @@
+package omap_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/alex023/basekit/container/omap"
+)
+
+func rangeKeys(m omap.SortedMap[int, int], lo, hi int, opts ...omap.RangeOption) []int {
+	keys := []int{}
+	m.Range(lo, hi, func(k, _ int) bool {
+		keys = append(keys, k)
+		return true
+	}, opts...)
+	return keys
+}
+
+func TestRange(t *testing.T) {
+	m := evenMap(10) // 0, 2, ..., 18
+	tests := []struct {
+		lo, hi int
+		opts   []omap.RangeOption
+		want   []int
+	}{
+		{4, 10, nil, []int{4, 6, 8}},
+		{4, 10, []omap.RangeOption{omap.HiInclusive}, []int{4, 6, 8, 10}},
+		{4, 10, []omap.RangeOption{omap.LoExclusive}, []int{6, 8}},
+		{4, 10, []omap.RangeOption{omap.LoExclusive | omap.HiInclusive}, []int{6, 8, 10}},
+		{3, 9, nil, []int{4, 6, 8}},
+		{-10, 3, nil, []int{0, 2}},
+		{15, 100, nil, []int{16, 18}},
+		{5, 5, []omap.RangeOption{omap.HiInclusive}, []int{}},
+		{6, 6, []omap.RangeOption{omap.HiInclusive}, []int{6}},
+		{8, 4, nil, []int{}},
+		{4, 10, []omap.RangeOption{omap.Reverse}, []int{8, 6, 4}},
+		{4, 10, []omap.RangeOption{omap.Reverse, omap.HiInclusive, omap.LoExclusive}, []int{10, 8, 6}},
+		{-10, 100, []omap.RangeOption{omap.Reverse}, []int{18, 16, 14, 12, 10, 8, 6, 4, 2, 0}},
+	}
+	for _, tt := range tests {
+		if got := rangeKeys(m, tt.lo, tt.hi, tt.opts...); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Range(%d, %d, %v) = %v, should be %v", tt.lo, tt.hi, tt.opts, got, tt.want)
+		}
+	}
+}
+
+func TestRangeStop(t *testing.T) {
+	m := evenMap(10)
+	var keys []int
+	m.Range(0, 100, func(k, _ int) bool {
+		keys = append(keys, k)
+		return len(keys) < 3
+	})
+	if want := []int{0, 2, 4}; !reflect.DeepEqual(keys, want) {
+		t.Errorf("got %v, should be %v", keys, want)
+	}
+}
+
+func TestDeleteRange(t *testing.T) {
+	m := evenMap(100) // 0, 2, ..., 198
+	if n := m.DeleteRange(10, 50); n != 20 {
+		t.Errorf("deleted %d, should be 20", n)
+	}
+	if n := m.DeleteRange(60, 70, omap.HiInclusive, omap.Reverse); n != 6 {
+		t.Errorf("deleted %d, should be 6", n)
+	}
+	if m.Len() != 74 {
+		t.Errorf("map len %d should be 74", m.Len())
+	}
+	for k := range m.All() {
+		if k >= 10 && k < 50 || k >= 60 && k <= 70 {
+			t.Errorf("%d should be deleted", k)
+		}
+	}
+	if got := rangeKeys(m, 0, 12); !reflect.DeepEqual(got, []int{0, 2, 4, 6, 8}) {
+		t.Errorf("unexpected keys %v", got)
+	}
+	if n := m.DeleteRange(-1, 1000); n != 74 || m.Len() != 0 {
+		t.Errorf("deleted %d, %d left", n, m.Len())
+	}
+}
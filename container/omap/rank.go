@@ -0,0 +1,52 @@
+package omap
+
+// Rank 返回map中小于key的元素数量。key存在时，即为它按升序排列的位置（从0开始）。
+// 复杂度为O(log n)。
+func (m *OrderedMap[K, V]) Rank(key K) int {
+	return m.count(key, false)
+}
+
+// Select 返回按升序排列的第k个元素（从0开始），k超出范围时found为false。
+// 复杂度为O(log n)。
+func (m *OrderedMap[K, V]) Select(k int) (key K, value V, found bool) {
+	if k < 0 || k >= m.length {
+		return key, value, false
+	}
+	root := m.root
+	for {
+		left := sizeOf(root.left)
+		if k < left {
+			root = root.left
+		} else if k > left {
+			k -= left + 1
+			root = root.right
+		} else {
+			return root.key, root.value, true
+		}
+	}
+}
+
+// CountRange 返回区间内的元素数量，区间的含义与Range相同。复杂度为O(log n)。
+func (m *OrderedMap[K, V]) CountRange(lo, hi K, opts ...RangeOption) int {
+	opt := combine(opts)
+	n := m.count(hi, opt&HiInclusive != 0) - m.count(lo, opt&LoExclusive != 0)
+	if n < 0 {
+		n = 0
+	}
+	return n
+}
+
+// count 返回小于key的元素数量，orEqual为true时包含等于key的元素。
+func (m *OrderedMap[K, V]) count(key K, orEqual bool) int {
+	n := 0
+	for root := m.root; root != nil; {
+		c := m.compare(key, root.key)
+		if c < 0 || c == 0 && !orEqual {
+			root = root.left
+		} else {
+			n += sizeOf(root.left) + 1
+			root = root.right
+		}
+	}
+	return n
+}
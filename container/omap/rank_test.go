@@ -0,0 +1,73 @@
+package omap_test
+
+import (
+	"math/rand"
+	"sort"
+	"testing"
+
+	"github.com/alex023/basekit/container/omap"
+)
+
+func TestRankSelect(t *testing.T) {
+	r := rand.New(rand.NewSource(2))
+	m := omap.NewOrdered[int, int]()
+	set := make(map[int]bool)
+	for i := 0; i < 5000; i++ {
+		k := r.Intn(2000)
+		if r.Intn(3) == 0 {
+			m.Delete(k)
+			delete(set, k)
+		} else {
+			m.Insert(k, -k)
+			set[k] = true
+		}
+	}
+	keys := make([]int, 0, len(set))
+	for k := range set {
+		keys = append(keys, k)
+	}
+	sort.Ints(keys)
+	for i, k := range keys {
+		if rank := m.Rank(k); rank != i {
+			t.Fatalf("Rank(%d) = %d, should be %d", k, rank, i)
+		}
+		if key, value, ok := m.Select(i); !ok || key != k || value != -k {
+			t.Fatalf("Select(%d) = %d, %d, %v, should be %d", i, key, value, ok, k)
+		}
+	}
+	for k := -1; k <= 2000; k++ {
+		if rank, want := m.Rank(k), sort.SearchInts(keys, k); rank != want {
+			t.Fatalf("Rank(%d) = %d, should be %d", k, rank, want)
+		}
+	}
+	for _, k := range []int{-1, len(keys)} {
+		if _, _, ok := m.Select(k); ok {
+			t.Errorf("Select(%d) should fail", k)
+		}
+	}
+}
+
+func TestCountRange(t *testing.T) {
+	m := evenMap(100) // 0, 2, ..., 198
+	tests := []struct {
+		lo, hi int
+		opts   []omap.RangeOption
+		want   int
+	}{
+		{10, 20, nil, 5},
+		{10, 20, []omap.RangeOption{omap.HiInclusive}, 6},
+		{10, 20, []omap.RangeOption{omap.LoExclusive}, 4},
+		{11, 19, nil, 4},
+		{-5, 1000, nil, 100},
+		{20, 10, nil, 0},
+		{7, 7, []omap.RangeOption{omap.HiInclusive}, 0},
+	}
+	for _, tt := range tests {
+		if got := m.CountRange(tt.lo, tt.hi, tt.opts...); got != tt.want {
+			t.Errorf("CountRange(%d, %d, %v) = %d, should be %d", tt.lo, tt.hi, tt.opts, got, tt.want)
+		}
+		if got := len(rangeKeys(m, tt.lo, tt.hi, tt.opts...)); got != tt.want {
+			t.Errorf("Range(%d, %d, %v) visited %d, should be %d", tt.lo, tt.hi, tt.opts, got, tt.want)
+		}
+	}
+}
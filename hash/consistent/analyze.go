@@ -0,0 +1,101 @@
+package consistent
+
+import (
+	"math"
+)
+
+// hashSpace 是32位哈希空间的大小
+const hashSpace = float64(math.MaxUint32) + 1
+
+// Distribution 是哈希环负载分布的分析结果，可以用来比较不同replicas、权重下的分布质量。
+type Distribution struct {
+	Shares      map[string]float64 //按名称统计的每个主机负责的哈希空间占比，总和为1
+	Mean        float64            //占比的平均值
+	StdDev      float64            //占比的标准差
+	RelStdDev   float64            //相对标准差，即StdDev/Mean
+	MaxMinRatio float64            //最大占比与最小占比的比值，越接近1越均匀
+}
+
+// Analyze 精确计算当前哈希环上每个主机负责的哈希空间占比及其统计量。
+// 占比只取决于环本身，不考虑MarkDown标记的主机。
+func (c *Ring[M]) Analyze() Distribution {
+	c.RLock()
+	defer c.RUnlock()
+	return c.analyze()
+}
+
+// SimulateReplicas 分析把replicas修改为replicasNum之后的分布，不修改当前的哈希环。
+func (c *Ring[M]) SimulateReplicas(replicasNum int) Distribution {
+	c.RLock()
+	next := c.clone()
+	c.RUnlock()
+	next.replicas = replicasNum
+	next.updateCircle()
+	return next.analyze()
+}
+
+// SimulateAdd 返回增加主机elt（权重为1）之后，需要迁移的key占全部key的比例，不修改当前的哈希环。
+func (c *Ring[M]) SimulateAdd(elt M) float64 {
+	return c.simulate(func(next *Ring[M]) bool { return next.add(c.key(elt), elt, 1) })
+}
+
+// SimulateRemove 返回删除主机elt之后，需要迁移的key占全部key的比例，不修改当前的哈希环。
+func (c *Ring[M]) SimulateRemove(elt M) float64 {
+	return c.simulate(func(next *Ring[M]) bool { return next.remove(c.key(elt)) })
+}
+
+// MoveFraction 返回迁移计划涉及的key占全部key的比例。
+func (p *Plan[M]) MoveFraction() float64 {
+	return moveFraction(p.Moves)
+}
+
+func (c *Ring[M]) simulate(fn func(next *Ring[M]) bool) float64 {
+	c.RLock()
+	defer c.RUnlock()
+	next := c.clone()
+	if !fn(next) {
+		return 0
+	}
+	next.updateCircle()
+	return moveFraction(diffCircle(c, next))
+}
+
+// analyze 调用前要加读锁
+func (c *Ring[M]) analyze() Distribution {
+	d := Distribution{Shares: make(map[string]float64, len(c.members))}
+	if len(c.circle) == 0 {
+		return d
+	}
+	for elt := range c.members {
+		d.Shares[elt] = 0
+	}
+	// 虚拟节点circle[i]负责(circle[i-1], circle[i]]，第一个虚拟节点还负责绕回的部分
+	prev := float64(c.circle[len(c.circle)-1]) - hashSpace
+	for _, p := range c.circle {
+		d.Shares[c.virtualMap[p]] += (float64(p) - prev) / hashSpace
+		prev = float64(p)
+	}
+
+	min, max := math.Inf(1), 0.0
+	for _, share := range d.Shares {
+		d.Mean += share
+		min = math.Min(min, share)
+		max = math.Max(max, share)
+	}
+	d.Mean /= float64(len(d.Shares))
+	for _, share := range d.Shares {
+		d.StdDev += (share - d.Mean) * (share - d.Mean)
+	}
+	d.StdDev = math.Sqrt(d.StdDev / float64(len(d.Shares)))
+	d.RelStdDev = d.StdDev / d.Mean
+	d.MaxMinRatio = max / min
+	return d
+}
+
+func moveFraction(moves []Move) float64 {
+	var total float64
+	for _, m := range moves {
+		total += float64(m.End) - float64(m.Start) + 1
+	}
+	return total / hashSpace
+}
@@ -0,0 +1,82 @@
+package consistent
+
+import (
+	"math"
+	"strconv"
+	"testing"
+)
+
+func TestAnalyze(t *testing.T) {
+	x := NewConsistent()
+	if d := x.Analyze(); len(d.Shares) != 0 {
+		t.Errorf("expected empty shares, got %v", d.Shares)
+	}
+	x.Add("abcdefg")
+	if d := x.Analyze(); d.Shares["abcdefg"] != 1 || d.MaxMinRatio != 1 || d.StdDev != 0 {
+		t.Errorf("unexpected distribution %+v", d)
+	}
+
+	for i := 0; i < 5; i++ {
+		x.Add("cache" + strconv.Itoa(i))
+	}
+	d := x.Analyze()
+	var sum float64
+	for _, share := range d.Shares {
+		sum += share
+	}
+	if math.Abs(sum-1) > 1e-9 || len(d.Shares) != 6 {
+		t.Errorf("unexpected shares %v", d.Shares)
+	}
+	if math.Abs(d.Mean-1.0/6) > 1e-9 || d.MaxMinRatio < 1 {
+		t.Errorf("unexpected distribution %+v", d)
+	}
+
+	// 分析结果应当与实际的key分布接近
+	counts := make(map[string]int)
+	const keys = 100000
+	for i := 0; i < keys; i++ {
+		m, _ := x.Get("user" + strconv.Itoa(i))
+		counts[m]++
+	}
+	for m, share := range d.Shares {
+		if math.Abs(float64(counts[m])/keys-share) > 0.02 {
+			t.Errorf("%s: share %.4f, but received %d of %d keys", m, share, counts[m], keys)
+		}
+	}
+}
+
+func TestSimulateReplicas(t *testing.T) {
+	x := NewConsistent()
+	for i := 0; i < 10; i++ {
+		x.Add("cache" + strconv.Itoa(i))
+	}
+	few, many := x.SimulateReplicas(5), x.SimulateReplicas(500)
+	if many.RelStdDev >= few.RelStdDev {
+		t.Errorf("more replicas should be more uniform: %f >= %f", many.RelStdDev, few.RelStdDev)
+	}
+	if x.replicas != constReplicas {
+		t.Error("SimulateReplicas should not modify the ring")
+	}
+}
+
+func TestSimulateAddRemove(t *testing.T) {
+	x := NewConsistent()
+	for i := 0; i < 4; i++ {
+		x.Add("cache" + strconv.Itoa(i))
+	}
+	share := x.Analyze().Shares["cache2"]
+	if f := x.SimulateRemove("cache2"); math.Abs(f-share) > 1e-9 {
+		t.Errorf("removing should move exactly the member's share: %f != %f", f, share)
+	}
+	if f := x.SimulateRemove("nonexistent"); f != 0 {
+		t.Errorf("expected 0, got %f", f)
+	}
+	f := x.SimulateAdd("cache4")
+	if f <= 0 || f >= 0.5 {
+		t.Errorf("unexpected move fraction %f", f)
+	}
+	if p := x.Plan(append(x.Members(), "cache4")); math.Abs(p.MoveFraction()-f) > 1e-9 {
+		t.Errorf("plan move fraction %f != %f", p.MoveFraction(), f)
+	}
+	checkNum(x.GetMachineNum(), 4, t)
+}
@@ -0,0 +1,60 @@
+package consistent
+
+import (
+	"errors"
+	"hash/fnv"
+)
+
+// Balancer 是各种一致性负载均衡算法的公共接口，便于通过配置切换算法，并在同一组基准测试中比较。
+//
+// Consistent（哈希环）、Rendezvous、Jump与Maglev都实现了该接口，它们都是并发安全的。
+type Balancer interface {
+	// Add 增加一个物理服务器，已经存在时不做任何修改。
+	Add(elt string)
+	// Remove 删除一个物理服务器，不存在时不做任何修改。
+	Remove(elt string)
+	// Get 返回name对应的物理服务器，没有服务器时返回ErrEmptyCircle。
+	Get(name string) (string, error)
+	// GetN 返回name对应的最多n个不同的物理服务器，第一个与Get的结果相同。
+	GetN(name string, n int) ([]string, error)
+}
+
+// 负载均衡算法的名称，用于NewBalancer
+const (
+	AlgorithmRing       = "ring"
+	AlgorithmRendezvous = "rendezvous"
+	AlgorithmJump       = "jump"
+	AlgorithmMaglev     = "maglev"
+)
+
+// ErrUnknownAlgorithm 表示NewBalancer不支持给定的算法名称。
+var ErrUnknownAlgorithm = errors.New("unknown balancer algorithm")
+
+// NewBalancer 根据算法名称创建一个空的Balancer，各算法使用默认参数。
+func NewBalancer(algorithm string) (Balancer, error) {
+	switch algorithm {
+	case AlgorithmRing:
+		return NewConsistent(), nil
+	case AlgorithmRendezvous:
+		return NewRendezvous(), nil
+	case AlgorithmJump:
+		return NewJump(), nil
+	case AlgorithmMaglev:
+		return NewMaglev(DefaultMaglevTableSize), nil
+	}
+	return nil, ErrUnknownAlgorithm
+}
+
+// hash64 计算s的64位FNV-1a哈希值。
+func hash64(s string) uint64 {
+	h := fnv.New64a()
+	h.Write([]byte(s))
+	return h.Sum64()
+}
+
+var (
+	_ Balancer = (*Consistent)(nil)
+	_ Balancer = (*Rendezvous)(nil)
+	_ Balancer = (*Jump)(nil)
+	_ Balancer = (*Maglev)(nil)
+)
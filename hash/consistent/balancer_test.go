@@ -0,0 +1,182 @@
+package consistent
+
+import (
+	"strconv"
+	"testing"
+)
+
+var algorithms = []string{AlgorithmRing, AlgorithmRendezvous, AlgorithmJump, AlgorithmMaglev}
+
+func newTestBalancer(t testing.TB, algorithm string, members int) Balancer {
+	b, err := NewBalancer(algorithm)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < members; i++ {
+		b.Add("cache" + strconv.Itoa(i))
+	}
+	return b
+}
+
+func TestNewBalancerUnknown(t *testing.T) {
+	if _, err := NewBalancer("random"); err != ErrUnknownAlgorithm {
+		t.Errorf("expected ErrUnknownAlgorithm, got %v", err)
+	}
+}
+
+func TestBalancerEmpty(t *testing.T) {
+	for _, algorithm := range algorithms {
+		b := newTestBalancer(t, algorithm, 0)
+		if _, err := b.Get("ggg"); err != ErrEmptyCircle {
+			t.Errorf("%s: expected ErrEmptyCircle, got %v", algorithm, err)
+		}
+		if _, err := b.GetN("ggg", 2); err != ErrEmptyCircle {
+			t.Errorf("%s: expected ErrEmptyCircle, got %v", algorithm, err)
+		}
+	}
+}
+
+func TestBalancerGetN(t *testing.T) {
+	for _, algorithm := range algorithms {
+		b := newTestBalancer(t, algorithm, 5)
+		for i := 0; i < 1000; i++ {
+			name := "user" + strconv.Itoa(i)
+			first, err := b.Get(name)
+			if err != nil {
+				t.Fatal(err)
+			}
+			members, err := b.GetN(name, 8)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(members) != 5 || members[0] != first {
+				t.Fatalf("%s: unexpected GetN %v, Get %s", algorithm, members, first)
+			}
+			set := make(map[string]bool)
+			for _, m := range members {
+				if set[m] {
+					t.Fatalf("%s: duplicate member in %v", algorithm, members)
+				}
+				set[m] = true
+			}
+		}
+	}
+}
+
+// 删除一个服务器之后，原先不在该服务器上的key，迁移的比例应当很小
+func TestBalancerRemove(t *testing.T) {
+	const keys = 10000
+	for _, algorithm := range algorithms {
+		b := newTestBalancer(t, algorithm, 10)
+		before := make([]string, keys)
+		for i := range before {
+			before[i], _ = b.Get("user" + strconv.Itoa(i))
+		}
+		b.Remove("cache3")
+		moved := 0
+		for i := range before {
+			after, _ := b.Get("user" + strconv.Itoa(i))
+			if after == "cache3" {
+				t.Fatalf("%s: key placed on removed member", algorithm)
+			}
+			if before[i] != "cache3" && before[i] != after {
+				moved++
+			}
+		}
+		// jump会额外迁移最后一个服务器的key，maglev会有少量表项变化
+		if float64(moved)/keys > 0.15 {
+			t.Errorf("%s: %d of %d keys on healthy members moved", algorithm, moved, keys)
+		}
+	}
+}
+
+func TestBalancerDistribution(t *testing.T) {
+	const keys = 100000
+	for _, algorithm := range algorithms {
+		b := newTestBalancer(t, algorithm, 5)
+		counts := make(map[string]int)
+		for i := 0; i < keys; i++ {
+			m, _ := b.Get("user" + strconv.Itoa(i))
+			counts[m]++
+		}
+		for m, count := range counts {
+			// 哈希环的虚拟节点较少，偏差较大
+			if count < keys/5/3 || count > keys/5*3 {
+				t.Errorf("%s: %s received %d keys", algorithm, m, count)
+			}
+		}
+	}
+}
+
+func benchmarkBalancerGet(b *testing.B, algorithm string, members int) {
+	x := newTestBalancer(b, algorithm, members)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		x.Get("user" + strconv.Itoa(i&1023))
+	}
+}
+
+func benchmarkBalancerGetN(b *testing.B, algorithm string, members int) {
+	x := newTestBalancer(b, algorithm, members)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		x.GetN("user"+strconv.Itoa(i&1023), 3)
+	}
+}
+
+func benchmarkBalancerCycle(b *testing.B, algorithm string, members int) {
+	x := newTestBalancer(b, algorithm, members)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		x.Add("foo")
+		x.Remove("foo")
+	}
+}
+
+func BenchmarkBalancerGetRing(b *testing.B) {
+	benchmarkBalancerGet(b, AlgorithmRing, 100)
+}
+
+func BenchmarkBalancerGetRendezvous(b *testing.B) {
+	benchmarkBalancerGet(b, AlgorithmRendezvous, 100)
+}
+
+func BenchmarkBalancerGetJump(b *testing.B) {
+	benchmarkBalancerGet(b, AlgorithmJump, 100)
+}
+
+func BenchmarkBalancerGetMaglev(b *testing.B) {
+	benchmarkBalancerGet(b, AlgorithmMaglev, 100)
+}
+
+func BenchmarkBalancerGetNRing(b *testing.B) {
+	benchmarkBalancerGetN(b, AlgorithmRing, 100)
+}
+
+func BenchmarkBalancerGetNRendezvous(b *testing.B) {
+	benchmarkBalancerGetN(b, AlgorithmRendezvous, 100)
+}
+
+func BenchmarkBalancerGetNJump(b *testing.B) {
+	benchmarkBalancerGetN(b, AlgorithmJump, 100)
+}
+
+func BenchmarkBalancerGetNMaglev(b *testing.B) {
+	benchmarkBalancerGetN(b, AlgorithmMaglev, 100)
+}
+
+func BenchmarkBalancerCycleRing(b *testing.B) {
+	benchmarkBalancerCycle(b, AlgorithmRing, 100)
+}
+
+func BenchmarkBalancerCycleRendezvous(b *testing.B) {
+	benchmarkBalancerCycle(b, AlgorithmRendezvous, 100)
+}
+
+func BenchmarkBalancerCycleJump(b *testing.B) {
+	benchmarkBalancerCycle(b, AlgorithmJump, 100)
+}
+
+func BenchmarkBalancerCycleMaglev(b *testing.B) {
+	benchmarkBalancerCycle(b, AlgorithmMaglev, 100)
+}
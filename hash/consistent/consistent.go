@@ -8,6 +8,8 @@
 //
 // 我们可以通过New来创建一致性哈希，通过Add、Remove来增加、删除服务器，通过Get来获取提供服务的物理机。
 // 需要注意的是,如果增删服务器，hash值将会重新计算（remap），会造成注册的服务器重建相关业务。
+// 服务器临时失效时，可以通过MarkDown标记为不可用，而不是删除它：查找时会跳过该服务器，
+// 恢复后通过MarkUp标记为可用，原有的分配关系也随之恢复。
 //
 // 相关技术的材料，可以查看：
 //	wikipedia:	http://en.wikipedia.org/wiki/Consistent_hashing
@@ -19,6 +21,7 @@ package consistent
 import (
 	"errors"
 	"hash/crc32"
+	"hash/fnv"
 	"sort"
 	"strconv"
 	"sync"
@@ -43,136 +46,283 @@ var ErrEmptyCircle = errors.New("empty circle")
 // 设置为20的时候，测试用例不会报错。但修改之后，将会有部分报错，因为验证值是设定死了，会有部分用例报错。
 var constReplicas = 20
 
-// Consistent 通过成员变量，保留一致性哈希环的struct.
-type Consistent struct {
+// Hash 标识计算哈希值所用的算法。
+type Hash uint8
+
+const (
+	// CRC32 使用crc32.ChecksumIEEE计算哈希值，是默认的算法。
+	CRC32 Hash = iota
+	// FNV32a 使用32位的FNV-1a计算哈希值。
+	FNV32a
+)
+
+// String 返回算法的名称。
+func (h Hash) String() string {
+	switch h {
+	case CRC32:
+		return "crc32"
+	case FNV32a:
+		return "fnv32a"
+	}
+	return "hash(" + strconv.Itoa(int(h)) + ")"
+}
+
+// Ring 是成员为任意类型M的一致性哈希环，每个成员通过key函数得到唯一的名称，环按名称计算虚拟节点。
+//
+// Get等查找方法直接返回成员本身，不需要再维护名称到连接、地址的映射。
+// 权重、标签、可用状态等属性都按照成员的名称设置。
+type Ring[M any] struct {
+	key        func(M) string    //成员到名称的映射
+	values     map[string]M      //名称到成员的映射
 	circle     circle            //环
 	replicas   int               //每一个主机的复制份数,即一个主机对应的虚拟节点数
+	hash       Hash              //哈希算法
 	virtualMap map[uint32]string //点到主机的映射
-	members    map[string]bool   //主机列表
+	members    map[string]int    //主机列表及其权重
+	down       map[string]bool   //被标记为不可用的主机
+	labels     map[string]Labels //主机的标签，比如机房、机架
+	pins       map[string]pin    //key的固定分配规则
+	version    uint64            //环的版本号，每次成员变化后递增
+
+	subscribers map[string]func(Event) //订阅环变化的客户端
+	pending     []Event                //释放写锁后需要通知的事件
 	sync.RWMutex
 }
 
+// Consistent 是成员为string的Ring，成员本身就是名称。
+type Consistent struct {
+	Ring[string]
+}
+
 // NewConsistent 基于默认的replicas定义，创建一个新的对象。
 //
-// 要改变replicas的值，可以在添加服务器节点之前，通过SetReplicas方法修改。
+// 要改变replicas的值，可以通过SetReplicas方法修改。
 func NewConsistent() *Consistent {
-	c := &Consistent{
-		circle:     circle{},
-		replicas:   constReplicas,
-		virtualMap: make(map[uint32]string),
-		members:    make(map[string]bool),
-	}
+	c := &Consistent{}
+	c.init(identity)
 	return c
 }
 
-// 修改constent的replicas属性，务必在Add方法之前使用
-func (c *Consistent) SetReplicas(replicasNum int) {
-	c.replicas = replicasNum
+// NewRing 基于默认的replicas定义，创建一个新的Ring，key返回成员的唯一名称。
+// 比如:
+//	type Server struct { Addr string; Conn net.Conn }
+//	ring := consistent.NewRing(func(s *Server) string { return s.Addr })
+func NewRing[M any](key func(M) string) *Ring[M] {
+	c := &Ring[M]{}
+	c.init(key)
+	return c
+}
+
+func (c *Ring[M]) init(key func(M) string) {
+	c.key = key
+	c.values = make(map[string]M)
+	c.circle = circle{}
+	c.replicas = constReplicas
+	c.virtualMap = make(map[uint32]string)
+	c.members = make(map[string]int)
+}
+
+func identity(s string) string { return s }
+
+// SetReplicas 修改consistent的replicas属性。已经添加的主机，其虚拟节点会重新计算。
+func (c *Ring[M]) SetReplicas(replicasNum int) {
+	c.Lock()
+	defer c.unlock()
+	c.change(RingRebuilt, "", func() bool {
+		if c.replicas == replicasNum {
+			return false
+		}
+		c.replicas = replicasNum
+		c.version++
+		return true
+	})
+}
+
+// SetHash 修改计算哈希值所用的算法。已经添加的主机，其虚拟节点会重新计算。
+func (c *Ring[M]) SetHash(h Hash) {
+	c.Lock()
+	defer c.unlock()
+	c.change(RingRebuilt, "", func() bool {
+		if c.hash == h {
+			return false
+		}
+		c.hash = h
+		c.version++
+		return true
+	})
 }
 
 // 获取一致性哈希中注册的主机节点数量
-func (c *Consistent) GetMachineNum() int {
+func (c *Ring[M]) GetMachineNum() int {
 	return len(c.members)
 }
 
+// Version 返回哈希环的版本号。每次成员、权重或者参数发生变化，版本号都会递增。
+func (c *Ring[M]) Version() uint64 {
+	c.RLock()
+	defer c.RUnlock()
+	return c.version
+}
+
 // eltKey 为输入的值生成符合虚拟服务器命名规则的key值。
-func (c *Consistent) eltKey(elt string, idx int) string {
+func (c *Ring[M]) eltKey(elt string, idx int) string {
 	// return elt + "|" + strconv.Itoa(idx)
 	return strconv.Itoa(idx) + elt
 }
 
-// Add 增加一个物理机到hash表中
-func (c *Consistent) Add(elt string) {
+// Add 增加一个物理机到hash表中，其权重为1
+func (c *Ring[M]) Add(elt M) {
+	c.Lock()
+	defer c.unlock()
+	k := c.key(elt)
+	c.change(MemberAdded, k, func() bool { return c.add(k, elt, 1) })
+}
+
+// AddWeight 按照给定的权重增加一个物理机，权重为n的主机拥有n倍的虚拟节点。
+// 权重小于1时按1处理；如果主机已经存在，则不做任何修改。
+func (c *Ring[M]) AddWeight(elt M, weight int) {
+	c.Lock()
+	defer c.unlock()
+	k := c.key(elt)
+	c.change(MemberAdded, k, func() bool { return c.add(k, elt, weight) })
+}
+
+// SetWeight 修改名称为elt的主机的权重，主机不存在时不做任何修改。权重小于1时按1处理。
+func (c *Ring[M]) SetWeight(elt string, weight int) {
 	c.Lock()
-	defer c.Unlock()
-	c.add(elt)
+	defer c.unlock()
+	if weight < 1 {
+		weight = 1
+	}
+	c.change(WeightChanged, elt, func() bool {
+		if w, ok := c.members[elt]; !ok || w == weight {
+			return false
+		}
+		c.members[elt] = weight
+		c.version++
+		return true
+	})
+}
+
+// Weight 返回名称为elt的主机的权重，主机不存在时返回0。
+func (c *Ring[M]) Weight(elt string) int {
+	c.RLock()
+	defer c.RUnlock()
+	return c.members[elt]
 }
-func (c *Consistent) add(elt string) {
+
+// 调用前要加锁，返回true表示主机列表发生了变化，需要通过change执行
+func (c *Ring[M]) add(elt string, value M, weight int) bool {
 	//避免重复插入
 	if _, ok := c.members[elt]; ok {
-		return
+		return false
 	}
-	for i := 0; i < c.replicas; i++ {
-		c.virtualMap[c.hashKey(c.eltKey(elt, i))] = elt
+	if weight < 1 {
+		weight = 1
 	}
-	c.members[elt] = true
-	c.updateCircle()
-
+	c.members[elt] = weight
+	c.values[elt] = value
+	c.version++
+	return true
 }
 
 // Remove removes an element from the hash.
-func (c *Consistent) Remove(elt string) {
+func (c *Ring[M]) Remove(elt M) {
 	c.Lock()
-	defer c.Unlock()
-	c.remove(elt)
+	defer c.unlock()
+	k := c.key(elt)
+	c.change(MemberRemoved, k, func() bool { return c.remove(k) })
 }
 
-// 调用前要加锁
-func (c *Consistent) remove(elt string) {
+// 调用前要加锁，返回true表示主机列表发生了变化，需要通过change执行
+func (c *Ring[M]) remove(elt string) bool {
 	if _, ok := c.members[elt]; !ok {
-		return
-	}
-
-	for i := 0; i < c.replicas; i++ {
-		delete(c.virtualMap, c.hashKey(c.eltKey(elt, i)))
+		return false
 	}
 	delete(c.members, elt)
-	c.updateCircle()
-
+	delete(c.values, elt)
+	delete(c.down, elt)
+	delete(c.labels, elt)
+	for k, p := range c.pins {
+		if p.member == elt {
+			delete(c.pins, k)
+		}
+	}
+	c.version++
+	return true
 }
 
 // 批量设置物理服务器到hash中。如果输入值与hash已经存在的值不一致，则以输入值为准。
+// 已经存在的服务器保留原有的权重，新增的服务器权重为1。
 //	Set sets all the elements in the hash.  If there are existing elements not
 //	present in elts, they will be removed.
-func (c *Consistent) Set(elts []string) {
+func (c *Ring[M]) Set(elts []M) {
 	c.Lock()
-	defer c.Unlock()
+	defer c.unlock()
+	c.set(elts)
+}
+
+// 调用前要加锁
+func (c *Ring[M]) set(elts []M) {
+	keys := make([]string, len(elts))
+	for i, v := range elts {
+		keys[i] = c.key(v)
+	}
 	for k := range c.members {
-		found := false
-		for _, v := range elts {
-			if k == v {
-				found = true
-				break
-			}
-		}
-		if !found {
-			c.remove(k)
+		if !sliceContainsMember(keys, k) {
+			c.change(MemberRemoved, k, func() bool { return c.remove(k) })
 		}
 	}
-	for _, v := range elts {
-		_, exists := c.members[v]
-		if exists {
-			continue
-		}
-		c.add(v)
+	for i, v := range elts {
+		k := keys[i]
+		c.change(MemberAdded, k, func() bool { return c.add(k, v, 1) })
 	}
 }
 
 // 以列表形式，获取所有的物理服务器
-func (c *Consistent) Members() []string {
+func (c *Ring[M]) Members() []M {
 	c.RLock()
 	defer c.RUnlock()
-	var m []string
-	for k := range c.members {
-		m = append(m, k)
+	var m []M
+	for _, v := range c.values {
+		m = append(m, v)
 	}
 	return m
 }
 
+// Member 返回名称为elt的主机。
+func (c *Ring[M]) Member(elt string) (m M, found bool) {
+	c.RLock()
+	defer c.RUnlock()
+	m, found = c.values[elt]
+	return
+}
+
 // Get 按照顺时针取值原则，获取name的哈希值最接近的物理服务器，即circle[i-1]<hash(name)<=circle[i]，返回circle[i]对应的物理服务。
-func (c *Consistent) Get(name string) (string, error) {
+//
+// 通过Pin固定分配的key优先返回对应的服务器；被MarkDown标记为不可用的服务器会被跳过，顺延到下一个可用的服务器。
+func (c *Ring[M]) Get(name string) (m M, err error) {
 	c.RLock()
 	defer c.RUnlock()
 	if len(c.members) == 0 {
-		return "", ErrEmptyCircle
+		return m, ErrEmptyCircle
+	}
+	if elt, ok := c.pinned(name); ok {
+		return c.values[elt], nil
 	}
 	key := c.hashKey(name)
 	i := c.search(key)
-	return c.virtualMap[c.circle[i]], nil
+	for j := 0; j < len(c.circle); j++ {
+		elt := c.virtualMap[c.circle[(i+j)%len(c.circle)]]
+		if !c.down[elt] {
+			return c.values[elt], nil
+		}
+	}
+	return m, ErrNoHealthyMember
 }
 
 // 通过二分查找
-func (c *Consistent) search(key uint32) (i int) {
+func (c *Ring[M]) search(key uint32) (i int) {
 	f := func(x int) bool {
 		return c.circle[x] >= key //不能是‘<’符号，可以使用‘>’或者‘>=’
 	}
@@ -183,88 +333,100 @@ func (c *Consistent) search(key uint32) (i int) {
 	return
 }
 
+// collect 从哈希值key开始顺时针遍历环，跳过不可用的服务器，返回最多n个不同服务器的名称。调用前要加读锁。
+func (c *Ring[M]) collect(key uint32, n int) []string {
+	if n > len(c.members) {
+		n = len(c.members)
+	}
+	if n <= 0 || len(c.circle) == 0 {
+		return nil
+	}
+	res := make([]string, 0, n)
+	i := c.search(key)
+	for j := 0; j < len(c.circle) && len(res) < n; j++ {
+		elt := c.virtualMap[c.circle[(i+j)%len(c.circle)]]
+		if !c.down[elt] && !sliceContainsMember(res, elt) {
+			res = append(res, elt)
+		}
+	}
+	return res
+}
+
 // GetTwo returns the two closest distinct elements to the name input in the circle.
-func (c *Consistent) GetTwo(name string) (string, string, error) {
+// A member pinned for name comes first; members marked down are skipped.
+func (c *Ring[M]) GetTwo(name string) (a M, b M, err error) {
 	c.RLock()
 	defer c.RUnlock()
 	if len(c.virtualMap) == 0 {
-		return "", "", ErrEmptyCircle
-	}
-	key := c.hashKey(name)
-	i := c.search(key)
-	a := c.virtualMap[c.circle[i]]
-
-	if c.GetMachineNum() == 1 {
-		return a, "", nil
+		return a, b, ErrEmptyCircle
 	}
-
-	start := i
-	var b string
-	for i = start + 1; i != start; i++ {
-		if i >= len(c.circle) {
-			i = 0
-		}
-		b = c.virtualMap[c.circle[i]]
-		if b != a {
-			break
-		}
+	res := c.lookup(name, 2)
+	switch len(res) {
+	case 0:
+		return a, b, ErrNoHealthyMember
+	case 1:
+		return c.values[res[0]], b, nil
 	}
-	return a, b, nil
+	return c.values[res[0]], c.values[res[1]], nil
 }
 
 // GetN returns the N closest distinct elements to the name input in the circle.
-func (c *Consistent) GetN(name string, n int) ([]string, error) {
+// A member pinned for name comes first; members marked down are skipped.
+func (c *Ring[M]) GetN(name string, n int) ([]M, error) {
 	c.RLock()
 	defer c.RUnlock()
 
 	if len(c.virtualMap) == 0 {
 		return nil, ErrEmptyCircle
 	}
-
-	if c.GetMachineNum() < n {
-		n = int(c.GetMachineNum())
+	res := c.lookup(name, n)
+	if len(res) == 0 && n > 0 {
+		return nil, ErrNoHealthyMember
 	}
+	return c.valuesOf(res), nil
+}
 
-	var (
-		key   = c.hashKey(name)
-		i     = c.search(key)
-		start = i
-		res   = make([]string, 0, n)
-		elem  = c.virtualMap[c.circle[i]]
-	)
-
-	res = append(res, elem)
-
-	if len(res) == n {
-		return res, nil
+// valuesOf 返回名称列表对应的成员，调用前要加读锁。
+func (c *Ring[M]) valuesOf(keys []string) []M {
+	if keys == nil {
+		return nil
 	}
-
-	for i = start + 1; i != start; i++ {
-		if i >= len(c.circle) {
-			i = 0
-		}
-		elem = c.virtualMap[c.circle[i]]
-		if !sliceContainsMember(res, elem) {
-			res = append(res, elem)
-		}
-		if len(res) == n {
-			break
-		}
+	res := make([]M, len(keys))
+	for i, k := range keys {
+		res[i] = c.values[k]
 	}
-
-	return res, nil
+	return res
 }
 
-func (c *Consistent) hashKey(key string) uint32 {
+func (c *Ring[M]) hashKey(key string) uint32 {
 	//	if len(key) < 64 {
 	//		var scratch [64]byte
 	//		copy(scratch[:], key)
 	//		return crc32.ChecksumIEEE(scratch[:len(key)])
 	//	}
+	if c.hash == FNV32a {
+		h := fnv.New32a()
+		h.Write([]byte(key))
+		return h.Sum32()
+	}
 	return crc32.ChecksumIEEE([]byte(key))
 }
 
-func (c *Consistent) updateCircle() {
+// updateCircle 根据主机列表及其权重，重新生成虚拟节点与环。
+//
+// 不同主机的虚拟节点哈希值冲突时，归属名称较小的主机。这样环的状态只取决于主机、权重、
+// replicas与哈希算法，与主机加入的先后顺序无关。
+func (c *Ring[M]) updateCircle() {
+	c.virtualMap = make(map[uint32]string, len(c.virtualMap))
+	for elt, weight := range c.members {
+		for i := 0; i < c.replicas*weight; i++ {
+			h := c.hashKey(c.eltKey(elt, i))
+			if old, ok := c.virtualMap[h]; ok && old < elt {
+				continue
+			}
+			c.virtualMap[h] = elt
+		}
+	}
 	//	hashes := c.circle[:0]
 	c.circle = circle{}
 	//	//reallocate if we're holding on to too much (1/4th)
@@ -285,5 +447,3 @@ func sliceContainsMember(set []string, member string) bool {
 	}
 	return false
 }
-
-// BUG(jc):没有实现热替换。某台服务器失效后，hash值需要全部重新计算，导致业务系统影响很大。
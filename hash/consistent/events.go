@@ -0,0 +1,102 @@
+package consistent
+
+// EventType 标识哈希环变化的类型。
+type EventType int
+
+const (
+	// MemberAdded 表示增加了一个主机。
+	MemberAdded EventType = iota + 1
+	// MemberRemoved 表示删除了一个主机。
+	MemberRemoved
+	// WeightChanged 表示主机的权重发生了变化。
+	WeightChanged
+	// RingRebuilt 表示replicas、哈希算法发生变化，或者从导出的状态恢复，整个环被重建。
+	RingRebuilt
+)
+
+// String 返回事件类型的名称。
+func (t EventType) String() string {
+	switch t {
+	case MemberAdded:
+		return "added"
+	case MemberRemoved:
+		return "removed"
+	case WeightChanged:
+		return "weight"
+	case RingRebuilt:
+		return "rebuilt"
+	}
+	return "unknown"
+}
+
+// Event 描述哈希环的一次变化。
+//
+// Moves为这次变化中归属发生改变的哈希区间，订阅者可以据此只让变化的缓存失效或者迁移。
+type Event struct {
+	Type    EventType
+	Member  string //发生变化的主机，RingRebuilt时为空
+	Weight  int    //主机变化后的权重，MemberRemoved时为0
+	Version uint64 //变化后环的版本号
+	Moves   []Move
+}
+
+// Subscribe 订阅哈希环的变化。要确保输入的clientID唯一，否则之前注册的回调会被替换。
+//
+// 回调在引起变化的goroutine中、释放锁之后同步执行，因此可以在回调中调用Get等方法，
+// 但不应长时间阻塞。并发修改时，事件的先后顺序以Version为准。
+func (c *Ring[M]) Subscribe(clientID string, callFunc func(Event)) {
+	c.Lock()
+	defer c.Unlock()
+	if c.subscribers == nil {
+		c.subscribers = make(map[string]func(Event))
+	}
+	c.subscribers[clientID] = callFunc
+}
+
+// Unsubscribe 取消clientID的订阅。
+func (c *Ring[M]) Unsubscribe(clientID string) {
+	c.Lock()
+	defer c.Unlock()
+	delete(c.subscribers, clientID)
+}
+
+// change 执行一次变更fn，fn返回true表示环的参数或主机列表发生了变化，此时重建环；
+// 如果存在订阅者，则比较变更前后的环，生成待通知的事件。调用前要加写锁。
+func (c *Ring[M]) change(typ EventType, member string, fn func() bool) {
+	var prev *Ring[M]
+	if len(c.subscribers) > 0 {
+		prev = c.clone()
+	}
+	if !fn() {
+		return
+	}
+	c.updateCircle()
+	if prev != nil {
+		c.pending = append(c.pending, Event{
+			Type:    typ,
+			Member:  member,
+			Weight:  c.members[member],
+			Version: c.version,
+			Moves:   diffCircle(prev, c),
+		})
+	}
+}
+
+// unlock 释放写锁，并把变更期间产生的事件通知给订阅者。
+func (c *Ring[M]) unlock() {
+	events := c.pending
+	c.pending = nil
+	var subs []func(Event)
+	if len(events) > 0 {
+		subs = make([]func(Event), 0, len(c.subscribers))
+		for _, fn := range c.subscribers {
+			subs = append(subs, fn)
+		}
+	}
+	c.Unlock()
+	for _, e := range events {
+		for _, fn := range subs {
+			fn(e)
+		}
+	}
+}
@@ -0,0 +1,57 @@
+package consistent
+
+import (
+	"testing"
+)
+
+func TestSubscribe(t *testing.T) {
+	x := NewConsistent()
+	x.Set([]string{"abcdefg", "hijklmn"})
+	var events []Event
+	x.Subscribe("cache", func(e Event) {
+		// 回调执行时已经释放了锁
+		x.Get("ggg")
+		events = append(events, e)
+	})
+
+	before := x.clone()
+	x.Add("opqrstu")
+	x.Add("opqrstu")
+	if len(events) != 1 {
+		t.Fatalf("expected 1 event, got %d", len(events))
+	}
+	e := events[0]
+	if e.Type != MemberAdded || e.Member != "opqrstu" || e.Weight != 1 || e.Version != x.Version() {
+		t.Errorf("unexpected event %+v", e)
+	}
+	checkMoves(t, before, &x.Ring, e.Moves)
+
+	before = x.clone()
+	x.SetWeight("abcdefg", 3)
+	e = events[len(events)-1]
+	if e.Type != WeightChanged || e.Member != "abcdefg" || e.Weight != 3 {
+		t.Errorf("unexpected event %+v", e)
+	}
+	checkMoves(t, before, &x.Ring, e.Moves)
+
+	events = nil
+	x.Set([]string{"abcdefg", "vwxyz"})
+	if len(events) != 3 {
+		t.Fatalf("expected 3 events, got %d", len(events))
+	}
+	if events[2].Type != MemberAdded || events[2].Member != "vwxyz" {
+		t.Errorf("unexpected event %+v", events[2])
+	}
+	for _, e := range events[:2] {
+		if e.Type != MemberRemoved || e.Weight != 0 {
+			t.Errorf("unexpected event %+v", e)
+		}
+	}
+
+	events = nil
+	x.Unsubscribe("cache")
+	x.Remove("vwxyz")
+	if len(events) != 0 {
+		t.Errorf("expected no events after unsubscribe, got %d", len(events))
+	}
+}
@@ -109,3 +109,24 @@ func ExampleConsistent_Remove() {
 	// user_bunny => cacheB
 	// user_stringer => cacheB
 }
+
+func ExampleNewRing() {
+	type server struct {
+		Addr string
+		Name string
+	}
+	c := consistent.NewRing(func(s *server) string { return s.Addr })
+	c.Add(&server{Addr: "cacheA", Name: "A"})
+	c.Add(&server{Addr: "cacheB", Name: "B"})
+	c.Add(&server{Addr: "cacheC", Name: "C"})
+	for _, u := range []string{"user_mcnulty", "user_bunny"} {
+		s, err := c.Get(u)
+		if err != nil {
+			log.Fatal(err)
+		}
+		fmt.Printf("%s => %s\n", u, s.Name)
+	}
+	// Output:
+	// user_mcnulty => A
+	// user_bunny => C
+}
@@ -0,0 +1,98 @@
+package consistent
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrNoHealthyMember 表示环中的服务器都被标记为不可用。
+var ErrNoHealthyMember = errors.New("no healthy member")
+
+// HealthChecker 检查服务器是否可用，member为服务器的名称。
+type HealthChecker interface {
+	Healthy(member string) bool
+}
+
+// HealthCheckFunc 把普通函数适配为HealthChecker。
+type HealthCheckFunc func(member string) bool
+
+// Healthy 调用f(member)。
+func (f HealthCheckFunc) Healthy(member string) bool {
+	return f(member)
+}
+
+// MarkDown 把名称为elt的服务器标记为不可用，但不从环中删除。
+//
+// Get、GetTwo、GetN会跳过不可用的服务器，顺延到下一个可用的服务器，
+// 其它服务器负责的key不受影响。服务器不存在时不做任何修改。
+func (c *Ring[M]) MarkDown(elt string) {
+	c.Lock()
+	defer c.Unlock()
+	if _, ok := c.members[elt]; !ok {
+		return
+	}
+	if c.down == nil {
+		c.down = make(map[string]bool)
+	}
+	c.down[elt] = true
+}
+
+// MarkUp 把服务器重新标记为可用，它原先负责的key会精确地回到它上面。
+func (c *Ring[M]) MarkUp(elt string) {
+	c.Lock()
+	defer c.Unlock()
+	delete(c.down, elt)
+}
+
+// IsDown 返回服务器是否被标记为不可用。
+func (c *Ring[M]) IsDown(elt string) bool {
+	c.RLock()
+	defer c.RUnlock()
+	return c.down[elt]
+}
+
+// StartHealthCheck 启动一个goroutine，每隔interval检查一次所有服务器，
+// 并根据checker的结果自动执行MarkDown、MarkUp。调用返回的函数可以停止检查，停止时会等待正在进行的检查完成。
+func (c *Ring[M]) StartHealthCheck(checker HealthChecker, interval time.Duration) (stop func()) {
+	var (
+		wg   sync.WaitGroup
+		once sync.Once
+		exit = make(chan struct{})
+	)
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			c.checkHealth(checker)
+			select {
+			case <-ticker.C:
+			case <-exit:
+				return
+			}
+		}
+	}()
+	return func() {
+		once.Do(func() { close(exit) })
+		wg.Wait()
+	}
+}
+
+// checkHealth 逐个检查服务器。检查期间不持有锁，以免阻塞查找。
+func (c *Ring[M]) checkHealth(checker HealthChecker) {
+	c.RLock()
+	keys := make([]string, 0, len(c.members))
+	for elt := range c.members {
+		keys = append(keys, elt)
+	}
+	c.RUnlock()
+	for _, elt := range keys {
+		if checker.Healthy(elt) {
+			c.MarkUp(elt)
+		} else {
+			c.MarkDown(elt)
+		}
+	}
+}
@@ -0,0 +1,106 @@
+package consistent
+
+import (
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestMarkDown(t *testing.T) {
+	x := NewConsistent()
+	x.Set([]string{"abcdefg", "hijklmn", "opqrstu"})
+	placement := make(map[string]string)
+	for i := 0; i < 1000; i++ {
+		name := "user" + strconv.Itoa(i)
+		placement[name], _ = x.Get(name)
+	}
+
+	x.MarkDown("hijklmn")
+	if !x.IsDown("hijklmn") {
+		t.Error("expected hijklmn to be down")
+	}
+	for name, before := range placement {
+		after, err := x.Get(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if after == "hijklmn" {
+			t.Fatalf("%s placed on down member", name)
+		}
+		if before != "hijklmn" && before != after {
+			t.Fatalf("%s moved from healthy %s to %s", name, before, after)
+		}
+		members, _ := x.GetN(name, 3)
+		if len(members) != 2 || sliceContainsMember(members, "hijklmn") {
+			t.Fatalf("unexpected GetN %v", members)
+		}
+		if members[0] != after {
+			t.Fatalf("GetN[0] %s != Get %s", members[0], after)
+		}
+	}
+
+	x.MarkUp("hijklmn")
+	for name, before := range placement {
+		if after, _ := x.Get(name); after != before {
+			t.Fatalf("%s: %s != %s after MarkUp", name, after, before)
+		}
+	}
+}
+
+func TestMarkDownAll(t *testing.T) {
+	x := NewConsistent()
+	x.Add("abcdefg")
+	x.MarkDown("abcdefg")
+	x.MarkDown("nonexistent")
+	if x.IsDown("nonexistent") {
+		t.Error("nonexistent member should not be marked down")
+	}
+	if _, err := x.Get("ggg"); err != ErrNoHealthyMember {
+		t.Errorf("expected ErrNoHealthyMember, got %v", err)
+	}
+	if _, _, err := x.GetTwo("ggg"); err != ErrNoHealthyMember {
+		t.Errorf("expected ErrNoHealthyMember, got %v", err)
+	}
+	if _, err := x.GetN("ggg", 2); err != ErrNoHealthyMember {
+		t.Errorf("expected ErrNoHealthyMember, got %v", err)
+	}
+	x.Remove("abcdefg")
+	x.Add("abcdefg")
+	if x.IsDown("abcdefg") {
+		t.Error("re-added member should be up")
+	}
+}
+
+func TestStartHealthCheck(t *testing.T) {
+	x := NewConsistent()
+	x.Set([]string{"abcdefg", "hijklmn"})
+	var (
+		mu      sync.Mutex
+		healthy = false
+	)
+	stop := x.StartHealthCheck(HealthCheckFunc(func(member string) bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return member != "hijklmn" || healthy
+	}), time.Millisecond)
+	defer stop()
+
+	waitFor := func(down bool) {
+		deadline := time.Now().Add(time.Second)
+		for x.IsDown("hijklmn") != down {
+			if time.Now().After(deadline) {
+				t.Fatalf("hijklmn down state never became %v", down)
+			}
+			time.Sleep(time.Millisecond)
+		}
+	}
+	waitFor(true)
+	if x.IsDown("abcdefg") {
+		t.Error("abcdefg should be up")
+	}
+	mu.Lock()
+	healthy = true
+	mu.Unlock()
+	waitFor(false)
+}
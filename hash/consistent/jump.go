@@ -0,0 +1,90 @@
+package consistent
+
+import (
+	"sync"
+)
+
+// Jump 基于Jump Consistent Hash（https://arxiv.org/abs/1406.2294）的负载均衡，
+// 不需要虚拟节点，几乎不占内存，分布也非常均匀。
+//
+// Jump只能在末尾增删桶，因此删除服务器时，把最后一个服务器移到被删除服务器的位置：
+// 除了被删除服务器的key之外，原先分配给最后一个服务器的key也会迁移。
+// 需要频繁删除任意服务器时，更适合使用Consistent或Rendezvous。
+type Jump struct {
+	buckets []string       //桶编号到服务器的映射
+	index   map[string]int //服务器到桶编号的映射
+	sync.RWMutex
+}
+
+// NewJump 创建一个空的Jump。
+func NewJump() *Jump {
+	return &Jump{index: make(map[string]int)}
+}
+
+// Add 增加一个物理服务器，作为新的最后一个桶。
+func (j *Jump) Add(elt string) {
+	j.Lock()
+	defer j.Unlock()
+	if _, ok := j.index[elt]; ok {
+		return
+	}
+	j.index[elt] = len(j.buckets)
+	j.buckets = append(j.buckets, elt)
+}
+
+// Remove 删除一个物理服务器，最后一个桶的服务器会移到它的位置。
+func (j *Jump) Remove(elt string) {
+	j.Lock()
+	defer j.Unlock()
+	i, ok := j.index[elt]
+	if !ok {
+		return
+	}
+	last := len(j.buckets) - 1
+	j.buckets[i] = j.buckets[last]
+	j.index[j.buckets[i]] = i
+	j.buckets = j.buckets[:last]
+	delete(j.index, elt)
+}
+
+// Get 返回name所在桶的服务器。
+func (j *Jump) Get(name string) (string, error) {
+	j.RLock()
+	defer j.RUnlock()
+	if len(j.buckets) == 0 {
+		return "", ErrEmptyCircle
+	}
+	return j.buckets[jumpHash(hash64(name), len(j.buckets))], nil
+}
+
+// GetN 返回name所在桶及其后续桶的服务器，最多n个。
+func (j *Jump) GetN(name string, n int) ([]string, error) {
+	j.RLock()
+	defer j.RUnlock()
+	if len(j.buckets) == 0 {
+		return nil, ErrEmptyCircle
+	}
+	if n > len(j.buckets) {
+		n = len(j.buckets)
+	}
+	if n <= 0 {
+		return nil, nil
+	}
+	b := jumpHash(hash64(name), len(j.buckets))
+	res := make([]string, n)
+	for i := range res {
+		res[i] = j.buckets[(b+i)%len(j.buckets)]
+	}
+	return res, nil
+}
+
+// jumpHash 把key映射到[0, buckets)之间的桶编号。
+func jumpHash(key uint64, buckets int) int {
+	var b, j int64 = -1, 0
+	for j < int64(buckets) {
+		b = j
+		key = key*2862933555777941757 + 1
+		j = int64(float64(b+1) * (float64(int64(1)<<31) / float64((key>>33)+1)))
+	}
+	return int(b)
+}
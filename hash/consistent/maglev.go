@@ -0,0 +1,140 @@
+package consistent
+
+import (
+	"hash/crc32"
+	"sort"
+	"sync"
+)
+
+// DefaultMaglevTableSize 是Maglev查找表的默认大小，必须是质数，并且远大于服务器数量。
+const DefaultMaglevTableSize = 65537
+
+// Maglev 基于Google Maglev（https://research.google/pubs/pub44824/）查找表的负载均衡。
+//
+// 查询只需要一次取模与一次数组访问；服务器变化时重建查找表，绝大部分表项保持不变。
+type Maglev struct {
+	size    int      //查找表大小
+	members []string //按名称排序的服务器，使查找表与加入顺序无关
+	table   []int    //查找表，表项为members的下标
+	sync.RWMutex
+}
+
+// NewMaglev 创建一个空的Maglev。tableSize不是质数时取不小于它的最小质数，不大于0时使用DefaultMaglevTableSize。
+func NewMaglev(tableSize int) *Maglev {
+	if tableSize <= 0 {
+		tableSize = DefaultMaglevTableSize
+	}
+	for !isPrime(tableSize) {
+		tableSize++
+	}
+	return &Maglev{size: tableSize}
+}
+
+// Add 增加一个物理服务器，并重建查找表。
+func (m *Maglev) Add(elt string) {
+	m.Lock()
+	defer m.Unlock()
+	i := sort.SearchStrings(m.members, elt)
+	if i < len(m.members) && m.members[i] == elt {
+		return
+	}
+	m.members = append(m.members, "")
+	copy(m.members[i+1:], m.members[i:])
+	m.members[i] = elt
+	m.populate()
+}
+
+// Remove 删除一个物理服务器，并重建查找表。
+func (m *Maglev) Remove(elt string) {
+	m.Lock()
+	defer m.Unlock()
+	i := sort.SearchStrings(m.members, elt)
+	if i == len(m.members) || m.members[i] != elt {
+		return
+	}
+	m.members = append(m.members[:i], m.members[i+1:]...)
+	m.populate()
+}
+
+// Get 返回name在查找表中对应的服务器。
+func (m *Maglev) Get(name string) (string, error) {
+	m.RLock()
+	defer m.RUnlock()
+	if len(m.members) == 0 {
+		return "", ErrEmptyCircle
+	}
+	return m.members[m.table[hash64(name)%uint64(m.size)]], nil
+}
+
+// GetN 从name对应的表项开始向后遍历查找表，返回最多n个不同的服务器。
+func (m *Maglev) GetN(name string, n int) ([]string, error) {
+	m.RLock()
+	defer m.RUnlock()
+	if len(m.members) == 0 {
+		return nil, ErrEmptyCircle
+	}
+	if n > len(m.members) {
+		n = len(m.members)
+	}
+	if n <= 0 {
+		return nil, nil
+	}
+	res := make([]string, 0, n)
+	start := int(hash64(name) % uint64(m.size))
+	for i := 0; i < m.size && len(res) < n; i++ {
+		elt := m.members[m.table[(start+i)%m.size]]
+		if !sliceContainsMember(res, elt) {
+			res = append(res, elt)
+		}
+	}
+	return res, nil
+}
+
+// populate 按照Maglev论文中的算法生成查找表：每个服务器按照各自的排列顺序轮流认领空闲的表项。调用前要加锁。
+func (m *Maglev) populate() {
+	n := len(m.members)
+	if n == 0 {
+		m.table = nil
+		return
+	}
+	size := uint64(m.size)
+	offsets := make([]uint64, n)
+	skips := make([]uint64, n)
+	nexts := make([]uint64, n)
+	for i, elt := range m.members {
+		offsets[i] = hash64(elt) % size
+		skips[i] = uint64(crc32.ChecksumIEEE([]byte(elt)))%(size-1) + 1
+	}
+	if m.table == nil {
+		m.table = make([]int, m.size)
+	}
+	for i := range m.table {
+		m.table[i] = -1
+	}
+	for filled := 0; ; {
+		for i := 0; i < n; i++ {
+			c := (offsets[i] + nexts[i]*skips[i]) % size
+			for m.table[c] >= 0 {
+				nexts[i]++
+				c = (offsets[i] + nexts[i]*skips[i]) % size
+			}
+			m.table[c] = i
+			nexts[i]++
+			if filled++; filled == m.size {
+				return
+			}
+		}
+	}
+}
+
+func isPrime(n int) bool {
+	if n < 2 {
+		return false
+	}
+	for i := 2; i*i <= n; i++ {
+		if n%i == 0 {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,102 @@
+package consistent
+
+import (
+	"errors"
+	"time"
+)
+
+// ErrMemberNotFound 表示指定名称的主机不存在。
+var ErrMemberNotFound = errors.New("member not found")
+
+// pin 是一条固定分配规则。
+type pin struct {
+	member string    //固定分配到的主机名称
+	expire time.Time //过期时间，零值表示永不过期
+}
+
+func (p pin) expired(now time.Time) bool {
+	return !p.expire.IsZero() && !now.Before(p.expire)
+}
+
+// Pin 把名称为name的key固定分配给名称为elt的主机，而不论哈希值落在哪里，比如VIP公会、大型赛事。
+// ttl不大于0时永不过期；同一个key再次Pin会替换原有的规则。主机不存在时返回ErrMemberNotFound。
+//
+// Get、GetTwo、GetN、GetNSpread会优先返回固定分配的主机；GetN等返回的其余主机仍按环的顺序排列。
+// 规则过期、主机被MarkDown或者被删除之后，key回到按哈希值分配；主机被删除时，指向它的规则也随之删除。
+func (c *Ring[M]) Pin(name, elt string, ttl time.Duration) error {
+	c.Lock()
+	defer c.Unlock()
+	if _, ok := c.members[elt]; !ok {
+		return ErrMemberNotFound
+	}
+	now := time.Now()
+	if c.pins == nil {
+		c.pins = make(map[string]pin)
+	}
+	for k, p := range c.pins {
+		if p.expired(now) {
+			delete(c.pins, k)
+		}
+	}
+	p := pin{member: elt}
+	if ttl > 0 {
+		p.expire = now.Add(ttl)
+	}
+	c.pins[name] = p
+	c.version++
+	return nil
+}
+
+// Unpin 删除key的固定分配规则。
+func (c *Ring[M]) Unpin(name string) {
+	c.Lock()
+	defer c.Unlock()
+	if _, ok := c.pins[name]; ok {
+		delete(c.pins, name)
+		c.version++
+	}
+}
+
+// Pinned 返回key固定分配到的主机名称，规则不存在或者已经过期时返回false。
+func (c *Ring[M]) Pinned(name string) (elt string, ok bool) {
+	c.RLock()
+	defer c.RUnlock()
+	p, ok := c.pins[name]
+	if !ok || p.expired(time.Now()) {
+		return "", false
+	}
+	return p.member, true
+}
+
+// pinned 返回key当前生效的固定分配主机：规则存在、未过期，并且主机可用。调用前要加读锁。
+func (c *Ring[M]) pinned(name string) (string, bool) {
+	if len(c.pins) == 0 {
+		return "", false
+	}
+	p, ok := c.pins[name]
+	if !ok || p.expired(time.Now()) || c.down[p.member] {
+		return "", false
+	}
+	return p.member, true
+}
+
+// lookup 返回key对应的最多n个不同的可用主机名称，固定分配的主机排在最前面。调用前要加读锁。
+func (c *Ring[M]) lookup(name string, n int) []string {
+	if n > len(c.members) {
+		n = len(c.members)
+	}
+	elt, ok := c.pinned(name)
+	if !ok || n <= 0 {
+		return c.collect(c.hashKey(name), n)
+	}
+	res := append(make([]string, 0, n), elt)
+	for _, k := range c.collect(c.hashKey(name), n) {
+		if len(res) == cap(res) {
+			break
+		}
+		if k != elt {
+			res = append(res, k)
+		}
+	}
+	return res
+}
@@ -0,0 +1,119 @@
+package consistent
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestPin(t *testing.T) {
+	x := NewConsistent()
+	x.Set([]string{"abcdefg", "hijklmn", "opqrstu"})
+	hashed, _ := x.Get("guild_vip")
+	target := "abcdefg"
+	if hashed == target {
+		target = "hijklmn"
+	}
+	if err := x.Pin("guild_vip", "nonexistent", 0); err != ErrMemberNotFound {
+		t.Errorf("expected ErrMemberNotFound, got %v", err)
+	}
+	if err := x.Pin("guild_vip", target, 0); err != nil {
+		t.Fatal(err)
+	}
+	if elt, ok := x.Pinned("guild_vip"); !ok || elt != target {
+		t.Errorf("unexpected pin %s", elt)
+	}
+	if elt, _ := x.Get("guild_vip"); elt != target {
+		t.Errorf("expected pinned %s, got %s", target, elt)
+	}
+	members, _ := x.GetN("guild_vip", 3)
+	if len(members) != 3 || members[0] != target {
+		t.Errorf("unexpected GetN %v", members)
+	}
+	// n大于主机数量时，最多返回全部主机
+	if members, _ := x.GetN("guild_vip", 1<<62); len(members) != 3 || members[0] != target {
+		t.Errorf("unexpected GetN %v", members)
+	}
+	if a, b, _ := x.GetTwo("guild_vip"); a != target || b == target {
+		t.Errorf("unexpected GetTwo %s, %s", a, b)
+	}
+
+	// 主机不可用时回到按哈希值分配
+	x.MarkDown(target)
+	if elt, _ := x.Get("guild_vip"); elt == target {
+		t.Error("pinned member is down, should fall back")
+	}
+	x.MarkUp(target)
+
+	x.Unpin("guild_vip")
+	if elt, _ := x.Get("guild_vip"); elt != hashed {
+		t.Errorf("expected %s after unpin, got %s", hashed, elt)
+	}
+
+	x.Pin("guild_vip", target, 0)
+	x.Remove(target)
+	if _, ok := x.Pinned("guild_vip"); ok {
+		t.Error("pin should be removed with its member")
+	}
+	x.Add(target)
+	if elt, _ := x.Get("guild_vip"); elt != hashed {
+		t.Errorf("expected %s after member re-added, got %s", hashed, elt)
+	}
+}
+
+func TestPinExpire(t *testing.T) {
+	x := NewConsistent()
+	x.Set([]string{"abcdefg", "hijklmn"})
+	hashed, _ := x.Get("tournament")
+	target := "abcdefg"
+	if hashed == target {
+		target = "hijklmn"
+	}
+	x.Pin("tournament", target, 20*time.Millisecond)
+	if elt, _ := x.Get("tournament"); elt != target {
+		t.Errorf("expected pinned %s, got %s", target, elt)
+	}
+	time.Sleep(30 * time.Millisecond)
+	if elt, _ := x.Get("tournament"); elt != hashed {
+		t.Errorf("expected %s after expiry, got %s", hashed, elt)
+	}
+	if _, ok := x.Pinned("tournament"); ok {
+		t.Error("pin should be expired")
+	}
+}
+
+func TestPinState(t *testing.T) {
+	x := newStateRing()
+	digest := x.Digest()
+	x.Pin("forever", "abcdefg", 0)
+	x.Pin("hour", "hijklmn", time.Hour)
+	if x.Digest() == digest {
+		t.Error("digest should change with pins")
+	}
+
+	data, err := x.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	y := new(Consistent)
+	if err := y.UnmarshalBinary(data); err != nil {
+		t.Fatal(err)
+	}
+	checkSameRing(t, x, y)
+	if elt, ok := y.Pinned("hour"); !ok || elt != "hijklmn" {
+		t.Errorf("unexpected pin %s", elt)
+	}
+
+	data, err = json.Marshal(x)
+	if err != nil {
+		t.Fatal(err)
+	}
+	z := new(Consistent)
+	if err := json.Unmarshal(data, z); err != nil {
+		t.Fatal(err)
+	}
+	checkSameRing(t, x, z)
+	if elt, ok := z.Pinned("forever"); !ok || elt != "abcdefg" {
+		t.Errorf("unexpected pin %s", elt)
+	}
+}
@@ -0,0 +1,138 @@
+package consistent
+
+import (
+	"errors"
+	"math"
+	"sort"
+)
+
+// ErrStalePlan 表示迁移计划生成之后，哈希环又发生了变化，该计划已经失效，需要重新生成。
+var ErrStalePlan = errors.New("stale plan")
+
+// Move 描述一段哈希区间的归属变化：哈希值位于[Start, End]闭区间内的key，将从From迁移到To。
+//
+// From、To是主机的名称。From为空，表示该区间原先没有归属（环为空）；To为空，表示变更后环为空。
+type Move struct {
+	Start, End uint32
+	From, To   string
+}
+
+// Plan 是由Ring.Plan生成的迁移计划。
+//
+// Moves按照区间起点升序排列，可以在切换流量之前，据此迁移缓存数据。
+type Plan[M any] struct {
+	Moves   []Move
+	members []M
+	version uint64
+}
+
+// Plan 计算把物理服务器设置为newMembers（语义同Set）之后，有哪些哈希区间会变更归属。
+// 该方法不会修改当前的哈希环，确认后可以通过Apply提交。
+func (c *Ring[M]) Plan(newMembers []M) *Plan[M] {
+	c.RLock()
+	defer c.RUnlock()
+	next := c.clone()
+	next.set(newMembers)
+	return &Plan[M]{
+		Moves:   diffCircle(c, next),
+		members: append([]M(nil), newMembers...),
+		version: c.version,
+	}
+}
+
+// Apply 原子地提交由Plan生成的迁移计划，订阅者会收到每个主机变化的事件。
+// 如果计划生成之后哈希环又发生了变化，则不做任何修改，返回ErrStalePlan。
+func (c *Ring[M]) Apply(p *Plan[M]) error {
+	c.Lock()
+	defer c.unlock()
+	if p.version != c.version {
+		return ErrStalePlan
+	}
+	c.set(p.members)
+	return nil
+}
+
+// clone 复制一份哈希环的状态，调用前要加读锁。
+func (c *Ring[M]) clone() *Ring[M] {
+	n := &Ring[M]{
+		key:        c.key,
+		values:     make(map[string]M, len(c.values)),
+		circle:     append(circle(nil), c.circle...),
+		replicas:   c.replicas,
+		hash:       c.hash,
+		virtualMap: make(map[uint32]string, len(c.virtualMap)),
+		members:    make(map[string]int, len(c.members)),
+		version:    c.version,
+	}
+	for k, v := range c.virtualMap {
+		n.virtualMap[k] = v
+	}
+	for k, v := range c.members {
+		n.members[k] = v
+	}
+	for k, v := range c.values {
+		n.values[k] = v
+	}
+	return n
+}
+
+// owner 返回哈希值key在环上归属的物理服务器，环为空时返回空字符串。
+func (c *Ring[M]) owner(key uint32) string {
+	if len(c.circle) == 0 {
+		return ""
+	}
+	return c.virtualMap[c.circle[c.search(key)]]
+}
+
+// diffCircle 比较两个哈希环，返回归属发生变化的区间。
+//
+// 两个环的虚拟节点合并之后，把整个哈希空间切分成若干段，每段内部在两个环上的归属都不变，
+// 逐段比较归属即可；相邻且迁移方向相同的段会合并成一个Move。
+func diffCircle[M any](prev, next *Ring[M]) []Move {
+	points := mergePoints(prev.circle, next.circle)
+	if len(points) == 0 {
+		return nil
+	}
+	var (
+		moves []Move
+		start uint32
+	)
+	emit := func(start, end uint32) {
+		from, to := prev.owner(end), next.owner(end)
+		if from == to {
+			return
+		}
+		if n := len(moves); n > 0 {
+			last := &moves[n-1]
+			if last.End+1 == start && last.From == from && last.To == to {
+				last.End = end
+				return
+			}
+		}
+		moves = append(moves, Move{Start: start, End: end, From: from, To: to})
+	}
+	for _, p := range points {
+		emit(start, p)
+		start = p + 1
+	}
+	// 最后一个虚拟节点之后的区间，顺时针绕回到环的第一个虚拟节点
+	if last := points[len(points)-1]; last != math.MaxUint32 {
+		emit(last+1, math.MaxUint32)
+	}
+	return moves
+}
+
+// mergePoints 合并两个有序的虚拟节点列表，并去除重复值。
+func mergePoints(a, b circle) []uint32 {
+	points := make([]uint32, 0, len(a)+len(b))
+	points = append(points, a...)
+	points = append(points, b...)
+	sort.Slice(points, func(i, j int) bool { return points[i] < points[j] })
+	res := points[:0]
+	for i, p := range points {
+		if i == 0 || p != points[i-1] {
+			res = append(res, p)
+		}
+	}
+	return res
+}
@@ -0,0 +1,96 @@
+package consistent
+
+import (
+	"strconv"
+	"testing"
+)
+
+// checkMoves 验证：落在Move区间内的key，归属由From变为To；其余key的归属不变。
+func checkMoves(t *testing.T, before, after *Ring[string], moves []Move) {
+	for i := 0; i < 10000; i++ {
+		name := "user" + strconv.Itoa(i)
+		h := before.hashKey(name)
+		from, to := before.owner(h), after.owner(h)
+		var mv *Move
+		for j := range moves {
+			if moves[j].Start <= h && h <= moves[j].End {
+				mv = &moves[j]
+				break
+			}
+		}
+		if mv == nil {
+			if from != to {
+				t.Fatalf("%s moved from %s to %s, but no move covers it", name, from, to)
+			}
+			continue
+		}
+		if mv.From != from || mv.To != to {
+			t.Fatalf("%s: move %+v, but actually %s => %s", name, *mv, from, to)
+		}
+	}
+}
+
+func TestPlanAdd(t *testing.T) {
+	x := NewConsistent()
+	x.Set([]string{"abcdefg", "hijklmn", "opqrstu"})
+	before := x.clone()
+	p := x.Plan([]string{"abcdefg", "hijklmn", "opqrstu", "vwxyz"})
+	if len(p.Moves) == 0 {
+		t.Fatal("expected moves")
+	}
+	for _, m := range p.Moves {
+		if m.To != "vwxyz" {
+			t.Errorf("unexpected move %+v", m)
+		}
+		if m.Start > m.End {
+			t.Errorf("invalid range %+v", m)
+		}
+	}
+	checkNum(x.GetMachineNum(), 3, t)
+	if err := x.Apply(p); err != nil {
+		t.Fatal(err)
+	}
+	checkNum(x.GetMachineNum(), 4, t)
+	checkMoves(t, before, &x.Ring, p.Moves)
+}
+
+func TestPlanRemove(t *testing.T) {
+	x := NewConsistent()
+	x.Set([]string{"abcdefg", "hijklmn", "opqrstu"})
+	before := x.clone()
+	p := x.Plan([]string{"abcdefg", "opqrstu"})
+	for _, m := range p.Moves {
+		if m.From != "hijklmn" {
+			t.Errorf("unexpected move %+v", m)
+		}
+	}
+	if err := x.Apply(p); err != nil {
+		t.Fatal(err)
+	}
+	checkMoves(t, before, &x.Ring, p.Moves)
+}
+
+func TestPlanEmpty(t *testing.T) {
+	x := NewConsistent()
+	p := x.Plan([]string{"abcdefg"})
+	if len(p.Moves) != 1 {
+		t.Fatalf("expected 1 move, got %d", len(p.Moves))
+	}
+	if m := p.Moves[0]; m.Start != 0 || m.End != 1<<32-1 || m.From != "" || m.To != "abcdefg" {
+		t.Errorf("unexpected move %+v", m)
+	}
+	if moves := x.Plan(nil).Moves; len(moves) != 0 {
+		t.Errorf("expected no moves, got %+v", moves)
+	}
+}
+
+func TestApplyStale(t *testing.T) {
+	x := NewConsistent()
+	x.Add("abcdefg")
+	p := x.Plan([]string{"abcdefg", "hijklmn"})
+	x.Add("opqrstu")
+	if err := x.Apply(p); err != ErrStalePlan {
+		t.Errorf("expected ErrStalePlan, got %v", err)
+	}
+	checkNum(x.GetMachineNum(), 2, t)
+}
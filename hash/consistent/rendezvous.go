@@ -0,0 +1,67 @@
+package consistent
+
+import (
+	"sort"
+	"sync"
+
+	"github.com/alex023/basekit/hash/hrw"
+)
+
+// Rendezvous 基于hrw包实现的最高随机权重（rendezvous）负载均衡。
+//
+// 删除服务器时，只有原先分配给它的key会迁移；GetN天然返回按权重排序的多个服务器。
+type Rendezvous struct {
+	nodes []string //按名称排序的服务器
+	sync.RWMutex
+}
+
+// NewRendezvous 创建一个空的Rendezvous。
+func NewRendezvous() *Rendezvous {
+	return &Rendezvous{}
+}
+
+// Add 增加一个物理服务器。
+func (r *Rendezvous) Add(elt string) {
+	r.Lock()
+	defer r.Unlock()
+	i := sort.SearchStrings(r.nodes, elt)
+	if i < len(r.nodes) && r.nodes[i] == elt {
+		return
+	}
+	r.nodes = append(r.nodes, "")
+	copy(r.nodes[i+1:], r.nodes[i:])
+	r.nodes[i] = elt
+}
+
+// Remove 删除一个物理服务器。
+func (r *Rendezvous) Remove(elt string) {
+	r.Lock()
+	defer r.Unlock()
+	i := sort.SearchStrings(r.nodes, elt)
+	if i == len(r.nodes) || r.nodes[i] != elt {
+		return
+	}
+	r.nodes = append(r.nodes[:i], r.nodes[i+1:]...)
+}
+
+// Get 返回name权重最高的服务器。
+func (r *Rendezvous) Get(name string) (string, error) {
+	res, err := r.GetN(name, 1)
+	if err != nil {
+		return "", err
+	}
+	return res[0], nil
+}
+
+// GetN 返回name权重最高的n个服务器，按权重降序排列。
+func (r *Rendezvous) GetN(name string, n int) ([]string, error) {
+	r.RLock()
+	defer r.RUnlock()
+	if len(r.nodes) == 0 {
+		return nil, ErrEmptyCircle
+	}
+	if n <= 0 {
+		return nil, nil
+	}
+	return hrw.TopNStrings(r.nodes, []byte(name), n), nil
+}
@@ -0,0 +1,62 @@
+package consistent
+
+import (
+	"strconv"
+	"testing"
+)
+
+type server struct {
+	addr string
+	id   int
+}
+
+func serverKey(s *server) string { return s.addr }
+
+func TestRing(t *testing.T) {
+	r := NewRing(serverKey)
+	if _, err := r.Get("ggg"); err != ErrEmptyCircle {
+		t.Errorf("expected ErrEmptyCircle, got %v", err)
+	}
+	servers := make([]*server, 3)
+	for i := range servers {
+		servers[i] = &server{addr: "10.0.0." + strconv.Itoa(i), id: i}
+		r.Add(servers[i])
+	}
+	checkNum(r.GetMachineNum(), 3, t)
+
+	// 成员的名称与Consistent一致时，分配结果也应当一致
+	x := NewConsistent()
+	x.Set([]string{"10.0.0.0", "10.0.0.1", "10.0.0.2"})
+	for i := 0; i < 1000; i++ {
+		name := "user" + strconv.Itoa(i)
+		s, err := r.Get(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		expected, _ := x.Get(name)
+		if s.addr != expected || servers[s.id] != s {
+			t.Fatalf("%s: got %+v, expected %s", name, s, expected)
+		}
+		all, _ := r.GetN(name, 3)
+		if len(all) != 3 || all[0] != s {
+			t.Fatalf("%s: unexpected GetN %v", name, all)
+		}
+	}
+
+	if s, ok := r.Member("10.0.0.1"); !ok || s != servers[1] {
+		t.Errorf("unexpected member %+v", s)
+	}
+	r.Remove(&server{addr: "10.0.0.1"})
+	if _, ok := r.Member("10.0.0.1"); ok {
+		t.Error("member should be removed")
+	}
+	checkNum(len(r.Members()), 2, t)
+
+	p := r.Plan([]*server{servers[0]})
+	if err := r.Apply(p); err != nil {
+		t.Fatal(err)
+	}
+	if s, _ := r.Get("ggg"); s != servers[0] {
+		t.Errorf("unexpected member %+v", s)
+	}
+}
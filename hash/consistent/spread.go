@@ -0,0 +1,93 @@
+package consistent
+
+import "maps"
+
+// Labels 是主机的标签，比如{"zone": "cn-east-1a", "rack": "r12", "host": "10.0.0.8"}。
+type Labels map[string]string
+
+// SetLabels 设置名称为elt的主机的标签，会替换原有的全部标签，主机不存在时不做任何修改。
+func (c *Ring[M]) SetLabels(elt string, labels Labels) {
+	c.Lock()
+	defer c.Unlock()
+	if _, ok := c.members[elt]; !ok || maps.Equal(c.labels[elt], labels) {
+		return
+	}
+	// 标签是导出状态与摘要的一部分，变化时要更新版本号
+	c.version++
+	if len(labels) == 0 {
+		delete(c.labels, elt)
+		return
+	}
+	if c.labels == nil {
+		c.labels = make(map[string]Labels)
+	}
+	c.labels[elt] = copyLabels(labels)
+}
+
+// GetLabels 返回名称为elt的主机标签的副本，主机不存在或者没有标签时返回nil。
+func (c *Ring[M]) GetLabels(elt string) Labels {
+	c.RLock()
+	defer c.RUnlock()
+	if l, ok := c.labels[elt]; ok {
+		return copyLabels(l)
+	}
+	return nil
+}
+
+// GetNSpread 与GetN类似，按照顺时针顺序返回最多n个不同的可用主机，
+// 但优先选择标签label取值互不相同的主机，使副本分散在不同的机房或机架上。
+//
+// 通过Pin固定分配的服务器总是排在第一个。标签取值不足n种时，剩余的副本按照顺时针顺序从其它主机中补齐，排在结果的末尾。没有该标签的主机，视为取值为空字符串。
+func (c *Ring[M]) GetNSpread(name string, n int, label string) ([]M, error) {
+	c.RLock()
+	defer c.RUnlock()
+
+	if len(c.virtualMap) == 0 {
+		return nil, ErrEmptyCircle
+	}
+	// 先按顺时针顺序取出全部可用主机，再分两轮挑选
+	candidates := c.lookup(name, len(c.members))
+	if len(candidates) == 0 && n > 0 {
+		return nil, ErrNoHealthyMember
+	}
+	if n > len(candidates) {
+		n = len(candidates)
+	}
+	if n <= 0 {
+		return nil, nil
+	}
+	var (
+		res    = make([]string, 0, n)
+		picked = make([]bool, len(candidates))
+		used   = make(map[string]bool, n)
+	)
+	for i, elt := range candidates {
+		if len(res) == n {
+			break
+		}
+		v := c.labels[elt][label]
+		if used[v] {
+			continue
+		}
+		used[v] = true
+		picked[i] = true
+		res = append(res, elt)
+	}
+	for i, elt := range candidates {
+		if len(res) == n {
+			break
+		}
+		if !picked[i] {
+			res = append(res, elt)
+		}
+	}
+	return c.valuesOf(res), nil
+}
+
+func copyLabels(labels Labels) Labels {
+	res := make(Labels, len(labels))
+	for k, v := range labels {
+		res[k] = v
+	}
+	return res
+}
@@ -0,0 +1,94 @@
+package consistent
+
+import (
+	"strconv"
+	"testing"
+)
+
+func newZoneRing() *Consistent {
+	x := NewConsistent()
+	for i := 0; i < 6; i++ {
+		elt := "cache" + strconv.Itoa(i)
+		x.Add(elt)
+		x.SetLabels(elt, Labels{"zone": "zone" + strconv.Itoa(i%3)})
+	}
+	return x
+}
+
+func TestGetNSpread(t *testing.T) {
+	x := newZoneRing()
+	for i := 0; i < 1000; i++ {
+		name := "user" + strconv.Itoa(i)
+		members, err := x.GetNSpread(name, 3, "zone")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(members) != 3 {
+			t.Fatalf("expected 3 members, got %v", members)
+		}
+		zones := make(map[string]bool)
+		for _, m := range members {
+			zones[x.GetLabels(m)["zone"]] = true
+		}
+		if len(zones) != 3 {
+			t.Fatalf("%s: members %v are not spread across zones", name, members)
+		}
+		if first, _ := x.Get(name); first != members[0] {
+			t.Fatalf("%s: first replica %s != Get %s", name, members[0], first)
+		}
+	}
+}
+
+func TestGetNSpreadFallback(t *testing.T) {
+	x := newZoneRing()
+	for i := 0; i < 100; i++ {
+		name := "user" + strconv.Itoa(i)
+		members, err := x.GetNSpread(name, 5, "zone")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(members) != 5 {
+			t.Fatalf("expected 5 members, got %v", members)
+		}
+		set := make(map[string]bool)
+		zones := make(map[string]bool)
+		for j, m := range members {
+			if set[m] {
+				t.Fatalf("duplicate member in %v", members)
+			}
+			set[m] = true
+			if j < 3 {
+				zones[x.GetLabels(m)["zone"]] = true
+			}
+		}
+		if len(zones) != 3 {
+			t.Fatalf("first 3 members %v are not spread across zones", members[:3])
+		}
+	}
+	members, _ := x.GetNSpread("ggg", 10, "rack")
+	checkNum(len(members), 6, t)
+	all, _ := x.GetN("ggg", 6)
+	for i := range all {
+		if all[i] != members[i] {
+			t.Errorf("without label values, expected GetN order %v, got %v", all, members)
+			break
+		}
+	}
+}
+
+func TestSetLabelsVersion(t *testing.T) {
+	x := newZoneRing()
+	v := x.Version()
+	x.SetLabels("cache0", Labels{"zone": "zone0"})
+	if x.Version() != v {
+		t.Errorf("unchanged labels should keep version %d, got %d", v, x.Version())
+	}
+	p := x.Plan([]string{"cache0"})
+	x.SetLabels("cache0", Labels{"zone": "zone9"})
+	if x.Version() == v {
+		t.Error("changed labels should bump version")
+	}
+	if err := x.Apply(p); err != ErrStalePlan {
+		t.Errorf("expected ErrStalePlan, got %v", err)
+	}
+}
@@ -0,0 +1,407 @@
+package consistent
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"sort"
+	"time"
+)
+
+var (
+	// ErrBadState 表示导入的哈希环数据格式不正确。
+	ErrBadState = errors.New("consistent: malformed ring state")
+	// ErrDigestMismatch 表示导入的哈希环数据与其携带的摘要不一致。
+	ErrDigestMismatch = errors.New("consistent: ring state digest mismatch")
+)
+
+// 二进制格式的魔数与格式版本
+const (
+	stateMagic         = "CNST"
+	stateFormatVersion = 1
+)
+
+// maxVirtualNodes 是导入的状态中虚拟节点总数（replicas与权重之积的和）的上限。
+// 摘要只能校验完整性，不能证明数据来源可信，超过上限的状态会被拒绝，避免重建环时耗尽CPU与内存。
+const maxVirtualNodes = 1 << 24
+
+// stateMember 是导出状态中的一个主机。
+type stateMember struct {
+	Name   string `json:"name"`
+	Weight int    `json:"weight"`
+	Labels Labels `json:"labels,omitempty"`
+}
+
+// statePin 是导出状态中的一条固定分配规则。
+type statePin struct {
+	Name   string     `json:"name"`
+	Member string     `json:"member"`
+	Expire *time.Time `json:"expire,omitempty"`
+}
+
+// stateJSON 是哈希环导出为JSON时的结构，主机与固定分配规则均按名称升序排列。
+type stateJSON struct {
+	Version  uint64        `json:"version"`
+	Hash     string        `json:"hash"`
+	Replicas int           `json:"replicas"`
+	Members  []stateMember `json:"members"`
+	Pins     []statePin    `json:"pins,omitempty"`
+	Digest   string        `json:"digest"`
+}
+
+// ringState 是哈希环中需要导出的内容，不包含版本号。
+type ringState struct {
+	hash     Hash
+	replicas int
+	members  map[string]int
+	labels   map[string]Labels
+	pins     map[string]pin
+}
+
+// state 返回当前需要导出的内容，已经过期的固定分配规则不会导出。调用前要加读锁。
+func (c *Consistent) state() ringState {
+	s := ringState{hash: c.hash, replicas: c.replicas, members: c.members, labels: c.labels}
+	now := time.Now()
+	for name, p := range c.pins {
+		if p.expired(now) {
+			continue
+		}
+		if s.pins == nil {
+			s.pins = make(map[string]pin, len(c.pins))
+		}
+		s.pins[name] = p
+	}
+	return s
+}
+
+// Digest 返回哈希环内容的摘要（十六进制的sha256）。
+//
+// 摘要只由主机、权重、标签、固定分配规则、replicas与哈希算法决定，不包含版本号，
+// 因此两个节点可以通过比较摘要，低成本地确认它们的哈希环完全一致。
+func (c *Consistent) Digest() string {
+	c.RLock()
+	defer c.RUnlock()
+	s := c.state()
+	sum := sha256.Sum256(s.appendContent(nil))
+	return hex.EncodeToString(sum[:])
+}
+
+// MarshalBinary 实现encoding.BinaryMarshaler，将哈希环导出为稳定的二进制格式。
+func (c *Consistent) MarshalBinary() ([]byte, error) {
+	c.RLock()
+	defer c.RUnlock()
+	buf := append([]byte(stateMagic), stateFormatVersion)
+	buf = binary.AppendUvarint(buf, c.version)
+	s := c.state()
+	content := s.appendContent(nil)
+	sum := sha256.Sum256(content)
+	buf = append(buf, content...)
+	return append(buf, sum[:]...), nil
+}
+
+// UnmarshalBinary 实现encoding.BinaryUnmarshaler，从MarshalBinary导出的数据恢复哈希环。
+// 数据格式不正确或者摘要不一致时返回错误，哈希环保持不变。
+func (c *Consistent) UnmarshalBinary(data []byte) error {
+	if len(data) < len(stateMagic)+1+sha256.Size ||
+		string(data[:len(stateMagic)]) != stateMagic ||
+		data[len(stateMagic)] != stateFormatVersion {
+		return ErrBadState
+	}
+	data = data[len(stateMagic)+1:]
+	version, n := binary.Uvarint(data)
+	if n <= 0 {
+		return ErrBadState
+	}
+	data = data[n:]
+	if len(data) < sha256.Size {
+		return ErrBadState
+	}
+	content, digest := data[:len(data)-sha256.Size], data[len(data)-sha256.Size:]
+	if sum := sha256.Sum256(content); !bytes.Equal(sum[:], digest) {
+		return ErrDigestMismatch
+	}
+	s, err := parseContent(content)
+	if err != nil {
+		return err
+	}
+	c.restore(version, s)
+	return nil
+}
+
+// MarshalJSON 实现json.Marshaler，主机按名称升序输出，并附带内容摘要。
+func (c *Consistent) MarshalJSON() ([]byte, error) {
+	c.RLock()
+	defer c.RUnlock()
+	s := c.state()
+	sum := sha256.Sum256(s.appendContent(nil))
+	res := stateJSON{
+		Version:  c.version,
+		Hash:     c.hash.String(),
+		Replicas: c.replicas,
+		Members:  sortMembers(s.members, s.labels),
+		Digest:   hex.EncodeToString(sum[:]),
+	}
+	for _, name := range sortedKeys(s.pins) {
+		p := s.pins[name]
+		sp := statePin{Name: name, Member: p.member}
+		if !p.expire.IsZero() {
+			expire := p.expire
+			sp.Expire = &expire
+		}
+		res.Pins = append(res.Pins, sp)
+	}
+	return json.Marshal(res)
+}
+
+// UnmarshalJSON 实现json.Unmarshaler，从MarshalJSON导出的数据恢复哈希环。
+// 如果数据中带有摘要，则恢复前会校验摘要是否一致。
+func (c *Consistent) UnmarshalJSON(data []byte) error {
+	var js stateJSON
+	if err := json.Unmarshal(data, &js); err != nil {
+		return err
+	}
+	hash, ok := parseHash(js.Hash)
+	if !ok || js.Replicas < 0 {
+		return ErrBadState
+	}
+	s := ringState{
+		hash:     hash,
+		replicas: js.Replicas,
+		members:  make(map[string]int, len(js.Members)),
+		labels:   make(map[string]Labels),
+	}
+	for _, m := range js.Members {
+		if _, dup := s.members[m.Name]; dup || m.Weight < 1 {
+			return ErrBadState
+		}
+		s.members[m.Name] = m.Weight
+		if len(m.Labels) > 0 {
+			s.labels[m.Name] = m.Labels
+		}
+	}
+	for _, p := range js.Pins {
+		if _, ok := s.members[p.Member]; !ok {
+			return ErrBadState
+		}
+		if _, dup := s.pins[p.Name]; dup {
+			return ErrBadState
+		}
+		if s.pins == nil {
+			s.pins = make(map[string]pin, len(js.Pins))
+		}
+		sp := pin{member: p.Member}
+		if p.Expire != nil {
+			sp.expire = time.Unix(0, p.Expire.UnixNano())
+		}
+		s.pins[p.Name] = sp
+	}
+	if s.oversized() {
+		return ErrBadState
+	}
+	if js.Digest != "" {
+		sum := sha256.Sum256(s.appendContent(nil))
+		if hex.EncodeToString(sum[:]) != js.Digest {
+			return ErrDigestMismatch
+		}
+	}
+	c.restore(js.Version, s)
+	return nil
+}
+
+// restore 用导入的状态替换当前的哈希环。
+func (c *Consistent) restore(version uint64, s ringState) {
+	c.Lock()
+	defer c.unlock()
+	if c.key == nil {
+		c.key = identity
+	}
+	c.change(RingRebuilt, "", func() bool {
+		c.version = version
+		c.hash = s.hash
+		c.replicas = s.replicas
+		c.members = s.members
+		c.values = make(map[string]string, len(s.members))
+		for elt := range s.members {
+			c.values[elt] = elt
+		}
+		c.labels = s.labels
+		c.pins = s.pins
+		for elt := range c.down {
+			if _, ok := s.members[elt]; !ok {
+				delete(c.down, elt)
+			}
+		}
+		return true
+	})
+}
+
+// sortMembers 返回按名称升序排列的主机及其权重、标签。
+func sortMembers(members map[string]int, labels map[string]Labels) []stateMember {
+	res := make([]stateMember, 0, len(members))
+	for _, name := range sortedKeys(members) {
+		res = append(res, stateMember{Name: name, Weight: members[name], Labels: labels[name]})
+	}
+	return res
+}
+
+// sortedKeys 返回升序排列的map的key。
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// appendContent 追加哈希环内容的规范编码：依次为哈希算法、replicas、主机数量、逐个主机（名称、权重、标签），
+// 固定分配规则数量、逐条规则（key、主机、过期时间）。主机、标签、规则均按名称升序排列。
+func (s *ringState) appendContent(buf []byte) []byte {
+	appendString := func(str string) {
+		buf = binary.AppendUvarint(buf, uint64(len(str)))
+		buf = append(buf, str...)
+	}
+	buf = append(buf, byte(s.hash))
+	buf = binary.AppendUvarint(buf, uint64(s.replicas))
+	buf = binary.AppendUvarint(buf, uint64(len(s.members)))
+	for _, name := range sortedKeys(s.members) {
+		appendString(name)
+		buf = binary.AppendUvarint(buf, uint64(s.members[name]))
+		labels := s.labels[name]
+		buf = binary.AppendUvarint(buf, uint64(len(labels)))
+		for _, k := range sortedKeys(labels) {
+			appendString(k)
+			appendString(labels[k])
+		}
+	}
+	buf = binary.AppendUvarint(buf, uint64(len(s.pins)))
+	for _, name := range sortedKeys(s.pins) {
+		p := s.pins[name]
+		appendString(name)
+		appendString(p.member)
+		var expire int64
+		if !p.expire.IsZero() {
+			expire = p.expire.UnixNano()
+		}
+		buf = binary.AppendVarint(buf, expire)
+	}
+	return buf
+}
+
+// oversized 判断状态的参数是否超出范围，或者虚拟节点总数超过maxVirtualNodes。
+func (s *ringState) oversized() bool {
+	if s.replicas < 0 || s.replicas > maxVirtualNodes {
+		return true
+	}
+	total := 0
+	for _, w := range s.members {
+		if w < 1 || w > maxVirtualNodes {
+			return true
+		}
+		if total += s.replicas * w; total > maxVirtualNodes {
+			return true
+		}
+	}
+	return false
+}
+
+// parseContent 是appendContent的逆过程。
+func parseContent(buf []byte) (s ringState, err error) {
+	bad := false
+	next := func() uint64 {
+		v, n := binary.Uvarint(buf)
+		if n <= 0 {
+			bad = true
+			return 0
+		}
+		buf = buf[n:]
+		return v
+	}
+	nextString := func() string {
+		l := next()
+		if bad || l > uint64(len(buf)) {
+			bad = true
+			return ""
+		}
+		str := string(buf[:l])
+		buf = buf[l:]
+		return str
+	}
+	if len(buf) == 0 {
+		return s, ErrBadState
+	}
+	s.hash, buf = Hash(buf[0]), buf[1:]
+	if _, ok := parseHash(s.hash.String()); !ok {
+		return s, ErrBadState
+	}
+	s.replicas = int(next())
+	count := next()
+	if bad || count > uint64(len(buf)) {
+		return s, ErrBadState
+	}
+	s.members = make(map[string]int, count)
+	s.labels = make(map[string]Labels)
+	for i := uint64(0); i < count && !bad; i++ {
+		name := nextString()
+		w := next()
+		if _, dup := s.members[name]; dup || w < 1 {
+			bad = true
+		}
+		s.members[name] = int(w)
+		nlabels := next()
+		if nlabels > uint64(len(buf)) {
+			bad = true
+		}
+		for j := uint64(0); j < nlabels && !bad; j++ {
+			if s.labels[name] == nil {
+				s.labels[name] = make(Labels, nlabels)
+			}
+			k := nextString()
+			s.labels[name][k] = nextString()
+		}
+	}
+	npins := next()
+	if npins > uint64(len(buf)) {
+		bad = true
+	}
+	for i := uint64(0); i < npins && !bad; i++ {
+		if s.pins == nil {
+			s.pins = make(map[string]pin, npins)
+		}
+		name := nextString()
+		p := pin{member: nextString()}
+		expire, n := binary.Varint(buf)
+		if n <= 0 {
+			bad = true
+			break
+		}
+		buf = buf[n:]
+		if expire != 0 {
+			p.expire = time.Unix(0, expire)
+		}
+		if _, dup := s.pins[name]; dup {
+			bad = true
+		}
+		if _, ok := s.members[p.member]; !ok {
+			bad = true
+		}
+		s.pins[name] = p
+	}
+	if bad || len(buf) != 0 || s.oversized() {
+		return ringState{}, ErrBadState
+	}
+	return s, nil
+}
+
+// parseHash 根据算法名称返回对应的Hash。
+func parseHash(name string) (Hash, bool) {
+	for _, h := range []Hash{CRC32, FNV32a} {
+		if h.String() == name {
+			return h, true
+		}
+	}
+	return 0, false
+}
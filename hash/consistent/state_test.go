@@ -0,0 +1,161 @@
+package consistent
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"reflect"
+	"sort"
+	"strconv"
+	"testing"
+)
+
+func newStateRing() *Consistent {
+	x := NewConsistent()
+	x.SetReplicas(50)
+	x.SetHash(FNV32a)
+	x.Add("abcdefg")
+	x.AddWeight("hijklmn", 3)
+	x.Add("opqrstu")
+	return x
+}
+
+func checkSameRing(t *testing.T, x, y *Consistent) {
+	if x.Version() != y.Version() {
+		t.Errorf("version %d != %d", x.Version(), y.Version())
+	}
+	if x.Digest() != y.Digest() {
+		t.Errorf("digest %s != %s", x.Digest(), y.Digest())
+	}
+	if !reflect.DeepEqual(x.circle, y.circle) || !reflect.DeepEqual(x.virtualMap, y.virtualMap) {
+		t.Error("restored circle differs")
+	}
+	for i := 0; i < 1000; i++ {
+		name := "user" + strconv.Itoa(i)
+		a, _ := x.Get(name)
+		b, _ := y.Get(name)
+		if a != b {
+			t.Fatalf("%s: %s != %s", name, a, b)
+		}
+	}
+}
+
+func TestStateBinary(t *testing.T) {
+	x := newStateRing()
+	data, err := x.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	y := new(Consistent)
+	if err := y.UnmarshalBinary(data); err != nil {
+		t.Fatal(err)
+	}
+	checkSameRing(t, x, y)
+	checkNum(y.Weight("hijklmn"), 3, t)
+
+	data[len(data)-sha256.Size-1]++
+	if err := y.UnmarshalBinary(data); err != ErrDigestMismatch {
+		t.Errorf("expected ErrDigestMismatch, got %v", err)
+	}
+	if err := y.UnmarshalBinary(data[:3]); err != ErrBadState {
+		t.Errorf("expected ErrBadState, got %v", err)
+	}
+}
+
+func TestStateJSON(t *testing.T) {
+	x := newStateRing()
+	data, err := json.Marshal(x)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var s stateJSON
+	if err := json.Unmarshal(data, &s); err != nil {
+		t.Fatal(err)
+	}
+	if s.Hash != "fnv32a" || s.Replicas != 50 || len(s.Members) != 3 || s.Digest != x.Digest() {
+		t.Errorf("unexpected state %s", data)
+	}
+	if !sort.SliceIsSorted(s.Members, func(i, j int) bool { return s.Members[i].Name < s.Members[j].Name }) {
+		t.Errorf("members are not sorted: %s", data)
+	}
+	y := NewConsistent()
+	if err := json.Unmarshal(data, y); err != nil {
+		t.Fatal(err)
+	}
+	checkSameRing(t, x, y)
+
+	s.Members[0].Weight = 2
+	data, _ = json.Marshal(s)
+	if err := json.Unmarshal(data, y); err != ErrDigestMismatch {
+		t.Errorf("expected ErrDigestMismatch, got %v", err)
+	}
+}
+
+func TestStateOversized(t *testing.T) {
+	y := NewConsistent()
+	data := []byte(`{"hash":"crc32","replicas":20,"members":[{"name":"a","weight":1000000000}]}`)
+	if err := json.Unmarshal(data, y); err != ErrBadState {
+		t.Errorf("expected ErrBadState, got %v", err)
+	}
+
+	s := ringState{hash: CRC32, replicas: 20, members: map[string]int{"a": 1 << 40}}
+	content := s.appendContent(nil)
+	sum := sha256.Sum256(content)
+	data = append([]byte(stateMagic), stateFormatVersion, 0)
+	data = append(append(data, content...), sum[:]...)
+	if err := y.UnmarshalBinary(data); err != ErrBadState {
+		t.Errorf("expected ErrBadState, got %v", err)
+	}
+	checkNum(y.GetMachineNum(), 0, t)
+}
+
+func TestDigestOrderIndependent(t *testing.T) {
+	x := NewConsistent()
+	x.Add(s1Collision)
+	x.Add(s2Collision)
+	y := NewConsistent()
+	y.Add(s2Collision)
+	y.Add(s1Collision)
+	if x.Digest() != y.Digest() || !reflect.DeepEqual(x.virtualMap, y.virtualMap) {
+		t.Error("rings built in different order should be identical")
+	}
+	y.SetWeight(s1Collision, 2)
+	if x.Digest() == y.Digest() {
+		t.Error("digest should change with weight")
+	}
+}
+
+const (
+	s1Collision = "abear"
+	s2Collision = "solidiform"
+)
+
+func TestStateLabels(t *testing.T) {
+	x := newStateRing()
+	digest := x.Digest()
+	x.SetLabels("hijklmn", Labels{"zone": "a", "rack": "r1"})
+	if x.Digest() == digest {
+		t.Error("digest should change with labels")
+	}
+	data, err := x.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	y := new(Consistent)
+	if err := y.UnmarshalBinary(data); err != nil {
+		t.Fatal(err)
+	}
+	checkSameRing(t, x, y)
+	if !reflect.DeepEqual(y.GetLabels("hijklmn"), Labels{"zone": "a", "rack": "r1"}) {
+		t.Errorf("unexpected labels %v", y.GetLabels("hijklmn"))
+	}
+
+	data, err = json.Marshal(x)
+	if err != nil {
+		t.Fatal(err)
+	}
+	z := new(Consistent)
+	if err := json.Unmarshal(data, z); err != nil {
+		t.Fatal(err)
+	}
+	checkSameRing(t, x, z)
+}
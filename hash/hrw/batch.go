@@ -0,0 +1,77 @@
+package hrw
+
+import "sync"
+
+// Batch places many keys on a fixed set of int nodes. It reuses its
+// selection buffers across keys and writes all placements into one slice,
+// so placing a batch allocates once per call instead of once per key.
+//
+// The fields must not be changed while PlaceBatch runs; otherwise a Batch
+// is safe for concurrent use.
+type Batch struct {
+	// Mixing is used to compute the weights of the nodes.
+	Mixing Mixing
+	// Workers is the number of goroutines which share the keys of a batch.
+	// Values below 2 place all keys on the calling goroutine.
+	Workers int
+
+	nodes []int
+}
+
+// NewBatch returns a Batch for the given nodes, using the Legacy mixing and
+// a single worker. The nodes are copied.
+func NewBatch(nodes []int) *Batch {
+	return &Batch{nodes: append([]int(nil), nodes...)}
+}
+
+// minBatchChunk is the smallest number of keys worth a goroutine.
+const minBatchChunk = 256
+
+// PlaceBatch returns the top n nodes for every key, as TopN with b.Mixing
+// would, in a flat slice with a stride of min(n, number of nodes): the nodes
+// of keys[i] are res[i*stride : (i+1)*stride].
+func (b *Batch) PlaceBatch(keys [][]byte, n int) []int {
+	stride := clamp(n, len(b.nodes))
+	res := make([]int, len(keys)*stride)
+	if stride == 0 {
+		return res
+	}
+	workers := b.Workers
+	if max := (len(keys) + minBatchChunk - 1) / minBatchChunk; workers > max {
+		workers = max
+	}
+	if workers < 2 {
+		b.place(res, keys, stride)
+		return res
+	}
+	var wg sync.WaitGroup
+	chunk := (len(keys) + workers - 1) / workers
+	for start := 0; start < len(keys); start += chunk {
+		end := start + chunk
+		if end > len(keys) {
+			end = len(keys)
+		}
+		wg.Add(1)
+		go func(start, end int) {
+			defer wg.Done()
+			b.place(res[start*stride:end*stride], keys[start:end], stride)
+		}(start, end)
+	}
+	wg.Wait()
+	return res
+}
+
+// place writes the top stride nodes of every key to res.
+func (b *Batch) place(res []int, keys [][]byte, stride int) {
+	h := make([]entry, 0, stride)
+	for i, key := range keys {
+		d := b.Mixing.digest(key)
+		h = selectTop(h[:0], len(b.nodes), stride, func(j int) entry {
+			return b.Mixing.entry(j, b.nodes[j], d)
+		})
+		out := res[i*stride : (i+1)*stride]
+		for j, e := range h {
+			out[j] = b.nodes[e.node]
+		}
+	}
+}
@@ -0,0 +1,82 @@
+package hrw
+
+import (
+	"reflect"
+	"strconv"
+	"testing"
+)
+
+func batchKeys(count int) [][]byte {
+	keys := make([][]byte, count)
+	for i := range keys {
+		keys[i] = []byte("key" + strconv.Itoa(i))
+	}
+	return keys
+}
+
+func TestPlaceBatch(t *testing.T) {
+	nodes := benchmarkServers(50)
+	keys := batchKeys(3000)
+	for _, mixing := range []Mixing{Legacy, SplitMix} {
+		for _, workers := range []int{0, 4} {
+			b := NewBatch(nodes)
+			b.Mixing, b.Workers = mixing, workers
+			res := b.PlaceBatch(keys, 3)
+			if len(res) != 3*len(keys) {
+				t.Fatalf("unexpected length %d", len(res))
+			}
+			for i, key := range keys {
+				if want := mixing.TopN(nodes, key, 3); !reflect.DeepEqual(res[i*3:i*3+3], want) {
+					t.Fatalf("mixing %d, workers %d, key %s: got %v, expected %v",
+						mixing, workers, key, res[i*3:i*3+3], want)
+				}
+			}
+		}
+	}
+}
+
+func TestPlaceBatchClamp(t *testing.T) {
+	b := NewBatch([]int{1, 2})
+	if res := b.PlaceBatch(batchKeys(5), 10); len(res) != 10 {
+		t.Errorf("expected stride 2, got %d results", len(res))
+	}
+	if res := b.PlaceBatch(batchKeys(5), 0); len(res) != 0 {
+		t.Errorf("expected no results, got %v", res)
+	}
+	if res := NewBatch(nil).PlaceBatch(batchKeys(5), 3); len(res) != 0 {
+		t.Errorf("expected no results, got %v", res)
+	}
+}
+
+func BenchmarkPlaceBatch(b *testing.B) {
+	batch := NewBatch(benchmarkServers(100))
+	keys := batchKeys(10000)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		batch.PlaceBatch(keys, 3)
+	}
+}
+
+func BenchmarkPlaceBatchWorkers(b *testing.B) {
+	batch := NewBatch(benchmarkServers(100))
+	batch.Workers = 4
+	keys := batchKeys(10000)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		batch.PlaceBatch(keys, 3)
+	}
+}
+
+func BenchmarkTopNPerKey(b *testing.B) {
+	nodes := benchmarkServers(100)
+	keys := batchKeys(10000)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for _, key := range keys {
+			TopN(nodes, key, 3)
+		}
+	}
+}
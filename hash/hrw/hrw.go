@@ -11,37 +11,89 @@
 package hrw
 
 import (
-	"hash/fnv"
 	"sort"
 )
 
 // SortByWeight returns the given set of nodes sorted in decreasing order of
-// their weight for the given key.
+// their weight for the given key. It uses the Legacy mixing.
+//
+// SortByWeight is kept for compatibility with existing placements; new code
+// should prefer SplitMix.SortByWeight, or SortStrings and SortNodes, which
+// accept string identities and weights.
 func SortByWeight(nodes []int, key []byte) []int {
-	h := fnv.New32a()
-	h.Write(key)
-	d := int32(h.Sum32())
+	return Legacy.SortByWeight(nodes, key)
+}
+
+// TopN returns the top N nodes in decreasing order of their weight for the
+// given key. It uses the Legacy mixing. If n exceeds len(nodes), all nodes
+// are returned.
+//
+// TopN selects the nodes with a bounded heap in O(len(nodes)·log n); use a
+// Scratch to avoid allocating on every call.
+func TopN(nodes []int, key []byte, n int) []int {
+	return Legacy.TopN(nodes, key, n)
+}
+
+// Mixing selects the function which computes the weight of an int node for
+// a key. Nodes with equal weights are ordered by node ID, so results never
+// depend on the order of the input.
+type Mixing int
+
+const (
+	// Legacy is the original 31-bit LCG over the node and the FNV-32a
+	// digest of the key. It is the default, so that existing placements do
+	// not change, but it produces many ties and a visibly skewed
+	// distribution for sequential node IDs.
+	Legacy Mixing = iota
+	// SplitMix combines the node and the FNV-64a digest of the key with the
+	// 64-bit SplitMix64 finalizer. It distributes keys uniformly for any node
+	// IDs, but places keys differently from Legacy.
+	SplitMix
+)
+
+// SortByWeight returns the given set of nodes sorted in decreasing order of
+// their weight for the given key, computed with m.
+func (m Mixing) SortByWeight(nodes []int, key []byte) []int {
+	d := m.digest(key)
 
 	entries := make(entryList, len(nodes))
 
 	for i, node := range nodes {
-		entries[i] = entry{node: node, weight: weight(int32(node), d)}
+		entries[i] = m.entry(i, node, d)
 	}
 
 	sort.Sort(entries)
 
 	sorted := make([]int, len(entries))
 	for i, e := range entries {
-		sorted[i] = e.node
+		sorted[i] = nodes[e.node]
 	}
 	return sorted
 }
 
 // TopN returns the top N nodes in decreasing order of their weight for the
-// given key.
-func TopN(nodes []int, key []byte, n int) []int {
-	// BUG(coda): TopN is not optimized.
-	return SortByWeight(nodes, key)[:n]
+// given key, computed with m. If n exceeds len(nodes), all nodes are
+// returned.
+func (m Mixing) TopN(nodes []int, key []byte, n int) []int {
+	k := clamp(n, len(nodes))
+	s := Scratch{Mixing: m, heap: make([]entry, 0, k), ints: make([]int, 0, k)}
+	return s.TopN(nodes, key, n)
+}
+
+// digest returns the digest of key used by m.
+func (m Mixing) digest(key []byte) uint64 {
+	if m == SplitMix {
+		return fnv64a(key)
+	}
+	return uint64(fnv32a(key))
+}
+
+// entry returns the entry of the i-th node for a key with digest d.
+func (m Mixing) entry(i, node int, d uint64) entry {
+	if m == SplitMix {
+		return entry{node: i, weight: float64(mix64(uint64(node), d) >> 11), tie: uint64(node)}
+	}
+	return entry{node: i, weight: float64(weight(int32(node), int32(d))), tie: uint64(node)}
 }
 
 func weight(s, d int32) int {
@@ -52,9 +104,12 @@ func weight(s, d int32) int {
 	return int(v)
 }
 
+// entry is a node, identified by its index in the input, and its weight for
+// a key. Entries with equal weights are ordered by tie, then by index.
 type entry struct {
 	node   int
-	weight int
+	weight float64
+	tie    uint64
 }
 
 type entryList []entry
@@ -64,7 +119,7 @@ func (l entryList) Len() int {
 }
 
 func (l entryList) Less(a, b int) bool {
-	return l[a].weight > l[b].weight
+	return better(l[a], l[b])
 }
 
 func (l entryList) Swap(a, b int) {
@@ -222,3 +222,78 @@ func benchmarkSortByWeight(b *testing.B, n int) int {
 	}
 	return x
 }
+
+// 测试连续整数节点在SplitMix下分布的均匀性
+func TestSplitMixUniformDistribution(t *testing.T) {
+	nodes := make([]int, 10)
+	for i := range nodes {
+		nodes[i] = i
+	}
+	counts := make(map[int]int)
+	key := make([]byte, 8)
+	keys := 200000
+
+	for i := 0; i < keys; i++ {
+		binary.BigEndian.PutUint64(key, uint64(i))
+		counts[SplitMix.TopN(nodes, key, 1)[0]]++
+	}
+
+	mean := float64(keys) / float64(len(nodes))
+	delta := mean * 0.03 // 3%
+	for node, count := range counts {
+		d := mean - float64(count)
+		if d > delta || (0-d) > delta {
+			t.Errorf(
+				"Node %d received %10d keys, expected %v (+/- %v)",
+				node, count, mean, delta,
+			)
+		}
+	}
+}
+
+// Legacy的结果要与原有实现一致，expected由引入Mixing之前的实现生成
+func TestMixingCompatible(t *testing.T) {
+	nodes := []int{1, 2, 3, 4, 5, 6, 7, 8, 9, 10}
+	placements := []struct {
+		key      string
+		nodes    []int
+		expected []int
+	}{
+		{"hello, world", nodes, []int{7, 8, 5, 4, 2, 1, 10, 9, 6, 3}},
+		{"user:42", nodes, []int{3, 8, 6, 5, 10, 9, 1, 4, 2, 7}},
+		{"session-7f3a", nodes, []int{2, 10, 3, 7, 9, 5, 8, 4, 1, 6}},
+		// 118与1443579的权重相同，原有实现按输入顺序排列，现在按节点编号排列
+		{"hello, world", []int{7, 118, 2, 1443579, 5}, []int{7, 5, 2, 118, 1443579}},
+		{"hello, world", []int{7, 1443579, 2, 118, 5}, []int{7, 5, 2, 118, 1443579}},
+	}
+	for _, p := range placements {
+		key := []byte(p.key)
+		if actual := SortByWeight(p.nodes, key); !reflect.DeepEqual(actual, p.expected) {
+			t.Errorf("SortByWeight(%v, %q) was %#v, but expected %#v", p.nodes, p.key, actual, p.expected)
+		}
+		if actual := Legacy.TopN(p.nodes, key, 3); !reflect.DeepEqual(actual, p.expected[:3]) {
+			t.Errorf("TopN(%v, %q, 3) was %#v, but expected %#v", p.nodes, p.key, actual, p.expected[:3])
+		}
+	}
+
+	key := []byte("hello, world")
+	var s Scratch
+	s.Mixing = SplitMix
+	expected := SplitMix.SortByWeight(nodes, key)
+	if actual := s.TopN(nodes, key, 3); !reflect.DeepEqual(actual, expected[:3]) {
+		t.Errorf("Was %#v, but expected %#v", actual, expected[:3])
+	}
+}
+
+// 权重相同时按节点编号排序，结果与输入顺序无关
+func TestTieBreak(t *testing.T) {
+	key := []byte("hello, world")
+	nodes := []int{9, 3, 7, 3, 1}
+	reversed := []int{1, 3, 7, 3, 9}
+	for _, mixing := range []Mixing{Legacy, SplitMix} {
+		a, b := mixing.SortByWeight(nodes, key), mixing.SortByWeight(reversed, key)
+		if !reflect.DeepEqual(a, b) {
+			t.Errorf("%v: %#v != %#v", mixing, a, b)
+		}
+	}
+}
@@ -0,0 +1,109 @@
+package hrw
+
+import (
+	"math"
+	"sort"
+)
+
+// Node is a node identified by a string, with a relative weight. A node with
+// weight 2 receives on average twice as many keys as a node with weight 1.
+// Nodes identified by bytes can use string(id) as their ID.
+type Node struct {
+	ID     string
+	Weight float64
+}
+
+// SortNodes returns the given set of nodes sorted in decreasing order of
+// their score for the given key.
+//
+// Scores are computed with the logarithmic method for weighted rendezvous
+// hashing: score = -weight / ln(h), where h is a uniform hash of the node ID
+// and the key in (0, 1). When a node is removed or its weight changes, only
+// the keys for which it was or becomes the top node are affected. Nodes with
+// a non-positive weight are treated as having weight 1.
+func SortNodes(nodes []Node, key []byte) []Node {
+	kh := fnv64a(key)
+	entries := make(entryList, len(nodes))
+	for i, node := range nodes {
+		entries[i] = nodeEntry(i, node, kh)
+	}
+	sort.Sort(entries)
+
+	sorted := make([]Node, len(entries))
+	for i, e := range entries {
+		sorted[i] = nodes[e.node]
+	}
+	return sorted
+}
+
+// TopNNodes returns the top N nodes in decreasing order of their score for
+// the given key. If n exceeds len(nodes), all nodes are returned.
+func TopNNodes(nodes []Node, key []byte, n int) []Node {
+	kh := fnv64a(key)
+	h := selectTop(nil, len(nodes), n, func(i int) entry {
+		return nodeEntry(i, nodes[i], kh)
+	})
+	top := make([]Node, len(h))
+	for i, e := range h {
+		top[i] = nodes[e.node]
+	}
+	return top
+}
+
+// SortStrings returns the given set of equally weighted nodes sorted in
+// decreasing order of their weight for the given key.
+func SortStrings(nodes []string, key []byte) []string {
+	kh := fnv64a(key)
+	entries := make(entryList, len(nodes))
+	for i, node := range nodes {
+		entries[i] = stringEntry(i, node, kh)
+	}
+	sort.Sort(entries)
+
+	sorted := make([]string, len(entries))
+	for i, e := range entries {
+		sorted[i] = nodes[e.node]
+	}
+	return sorted
+}
+
+// TopNStrings returns the top N equally weighted nodes in decreasing order of
+// their weight for the given key. If n exceeds len(nodes), all nodes are
+// returned.
+func TopNStrings(nodes []string, key []byte, n int) []string {
+	k := clamp(n, len(nodes))
+	s := Scratch{heap: make([]entry, 0, k), strings: make([]string, 0, k)}
+	return s.TopNStrings(nodes, key, n)
+}
+
+// nodeEntry returns the entry of the i-th node, weighted by its score for a
+// key hashed to kh. Equal scores are ordered by the hash of the node ID.
+func nodeEntry(i int, node Node, kh uint64) entry {
+	w := node.Weight
+	if w <= 0 {
+		w = 1
+	}
+	nh := fnv64aString(node.ID)
+	return entry{node: i, weight: -w / math.Log(unitFloat(mix64(nh, kh))), tie: nh}
+}
+
+// stringEntry returns the entry of the i-th equally weighted node for a key
+// hashed to kh.
+func stringEntry(i int, node string, kh uint64) entry {
+	nh := fnv64aString(node)
+	return entry{node: i, weight: float64(mix64(nh, kh) >> 11), tie: nh}
+}
+
+// mix64 combines a node hash and a key hash into a well distributed 64-bit
+// value, using the SplitMix64 finalizer.
+func mix64(node, key uint64) uint64 {
+	x := node ^ (key + 0x9e3779b97f4a7c15 + (node << 6) + (node >> 2))
+	x = (x ^ (x >> 30)) * 0xbf58476d1ce4e5b9
+	x = (x ^ (x >> 27)) * 0x94d049bb133111eb
+	return x ^ (x >> 31)
+}
+
+// unitFloat maps h to a float in the open interval (0, 1).
+func unitFloat(h uint64) float64 {
+	return (float64(h>>11) + 0.5) / (1 << 53)
+}
@@ -0,0 +1,103 @@
+package hrw
+
+import (
+	"encoding/binary"
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func ExampleTopNNodes() {
+	nodes := []Node{
+		{ID: "one.example.com", Weight: 1},
+		{ID: "two.example.com", Weight: 1},
+		{ID: "big.example.com", Weight: 4},
+	}
+	key := []byte("/examples/object-key")
+	for _, node := range TopNNodes(nodes, key, 2) {
+		fmt.Printf("trying GET %s%s\n", node.ID, key)
+	}
+
+	// Output:
+	// trying GET big.example.com/examples/object-key
+	// trying GET one.example.com/examples/object-key
+}
+
+func TestSortStrings(t *testing.T) {
+	key := []byte("hello, world")
+	nodes := []string{"a", "b", "c", "d", "e"}
+	sorted := SortStrings(nodes, key)
+	if len(sorted) != len(nodes) {
+		t.Fatalf("unexpected result %v", sorted)
+	}
+	// 结果只与节点集合有关，与输入顺序无关
+	reversed := []string{"e", "d", "c", "b", "a"}
+	if actual := SortStrings(reversed, key); !reflect.DeepEqual(actual, sorted) {
+		t.Errorf("Was %#v, but expected %#v", actual, sorted)
+	}
+	if actual := TopNStrings(nodes, key, 10); !reflect.DeepEqual(actual, sorted) {
+		t.Errorf("Was %#v, but expected %#v", actual, sorted)
+	}
+	if nodes[0] != "a" {
+		t.Error("input should not be modified")
+	}
+}
+
+// 删除一个节点时，只有原先分配给它的key会迁移
+func TestStringsRemove(t *testing.T) {
+	nodes := []string{"a", "b", "c", "d", "e"}
+	key := make([]byte, 8)
+	for i := 0; i < 10000; i++ {
+		binary.BigEndian.PutUint64(key, uint64(i))
+		before := TopNStrings(nodes, key, 1)[0]
+		after := TopNStrings(nodes[1:], key, 1)[0]
+		if before != "a" && before != after {
+			t.Fatalf("key %d moved from %s to %s", i, before, after)
+		}
+	}
+}
+
+func TestWeightedDistribution(t *testing.T) {
+	nodes := []Node{{"a", 1}, {"b", 2}, {"c", 3}, {"d", 4}}
+	counts := make(map[string]int)
+	key := make([]byte, 8)
+	keys := 100000
+	for i := 0; i < keys; i++ {
+		binary.BigEndian.PutUint64(key, uint64(i))
+		counts[TopNNodes(nodes, key, 1)[0].ID]++
+	}
+	for _, node := range nodes {
+		expected := float64(keys) * node.Weight / 10
+		delta := expected * 0.05
+		if d := float64(counts[node.ID]) - expected; d > delta || -d > delta {
+			t.Errorf("Node %s received %d keys, expected %v (+/- %v)", node.ID, counts[node.ID], expected, delta)
+		}
+	}
+}
+
+// 节点权重变化时，只有分配给它的key发生变化
+func TestWeightChange(t *testing.T) {
+	nodes := []Node{{"a", 1}, {"b", 1}, {"c", 1}}
+	heavier := []Node{{"a", 2}, {"b", 1}, {"c", 1}}
+	key := make([]byte, 8)
+	for i := 0; i < 10000; i++ {
+		binary.BigEndian.PutUint64(key, uint64(i))
+		before := TopNNodes(nodes, key, 1)[0].ID
+		after := TopNNodes(heavier, key, 1)[0].ID
+		if before != after && after != "a" {
+			t.Fatalf("key %d moved from %s to %s", i, before, after)
+		}
+	}
+}
+
+func BenchmarkSortStrings100(b *testing.B) {
+	key := []byte("hello, world")
+	nodes := make([]string, 100)
+	for i := range nodes {
+		nodes[i] = fmt.Sprintf("node%d", i)
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		SortStrings(nodes, key)
+	}
+}
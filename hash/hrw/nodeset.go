@@ -0,0 +1,244 @@
+package hrw
+
+import (
+	"container/list"
+	"errors"
+	"math"
+	"sync"
+)
+
+// ErrEmptySet is returned when looking up a key in a NodeSet without nodes.
+var ErrEmptySet = errors.New("hrw: empty node set")
+
+// NodeSet is a set of string identified, optionally weighted nodes, which
+// offers the same membership API as consistent.Consistent. The hash of every
+// node ID is computed once when the node is added, so a lookup only hashes
+// the key. A NodeSet is safe for concurrent use; its zero value is an empty
+// set without a cache.
+//
+// Placements are the same as those of TopNNodes for the same nodes.
+type NodeSet struct {
+	mu       sync.RWMutex
+	ids      []string
+	weights  []float64
+	seeds    []uint64
+	index    map[string]int
+	weighted bool // whether any weight differs from 1
+
+	cache *lruCache
+}
+
+// NewNodeSet returns an empty NodeSet. If cacheSize is positive, the results
+// of up to cacheSize hot lookups are cached until the membership changes.
+func NewNodeSet(cacheSize int) *NodeSet {
+	s := &NodeSet{}
+	if cacheSize > 0 {
+		s.cache = newLRUCache(cacheSize)
+	}
+	return s
+}
+
+// Add adds a node with weight 1. Adding an existing node does nothing.
+func (s *NodeSet) Add(id string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.index[id]; !ok {
+		s.set(id, 1)
+	}
+}
+
+// AddWeight adds a node with the given weight, or changes the weight of an
+// existing node. A non-positive weight is treated as 1.
+func (s *NodeSet) AddWeight(id string, weight float64) {
+	if weight <= 0 {
+		weight = 1
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.set(id, weight)
+}
+
+// set adds a node or changes its weight. The caller must hold s.mu.
+func (s *NodeSet) set(id string, weight float64) {
+	if i, ok := s.index[id]; ok {
+		if s.weights[i] == weight {
+			return
+		}
+		s.weights[i] = weight
+	} else {
+		if s.index == nil {
+			s.index = make(map[string]int)
+		}
+		s.index[id] = len(s.ids)
+		s.ids = append(s.ids, id)
+		s.weights = append(s.weights, weight)
+		s.seeds = append(s.seeds, fnv64aString(id))
+	}
+	s.changed()
+}
+
+// Remove removes a node. Only the keys for which it ranked among the
+// requested top nodes are affected.
+func (s *NodeSet) Remove(id string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	i, ok := s.index[id]
+	if !ok {
+		return
+	}
+	last := len(s.ids) - 1
+	s.ids[i], s.weights[i], s.seeds[i] = s.ids[last], s.weights[last], s.seeds[last]
+	s.index[s.ids[i]] = i
+	s.ids, s.weights, s.seeds = s.ids[:last], s.weights[:last], s.seeds[:last]
+	delete(s.index, id)
+	s.changed()
+}
+
+// Len returns the number of nodes.
+func (s *NodeSet) Len() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return len(s.ids)
+}
+
+// Members returns the IDs of all nodes, in no particular order.
+func (s *NodeSet) Members() []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return append([]string(nil), s.ids...)
+}
+
+// Get returns the node with the highest score for key.
+func (s *NodeSet) Get(key string) (string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if len(s.ids) == 0 {
+		return "", ErrEmptySet
+	}
+	if top, ok := s.cache.get(key, 1); ok {
+		return top[0], nil
+	}
+	kh := fnv64aString(key)
+	best := s.entry(0, kh)
+	for i := 1; i < len(s.ids); i++ {
+		if e := s.entry(i, kh); better(e, best) {
+			best = e
+		}
+	}
+	if s.cache != nil {
+		s.cache.add(key, 1, []string{s.ids[best.node]})
+	}
+	return s.ids[best.node], nil
+}
+
+// GetN returns the n nodes with the highest scores for key, in decreasing
+// order of score. If n exceeds the number of nodes, all nodes are returned.
+func (s *NodeSet) GetN(key string, n int) ([]string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if len(s.ids) == 0 {
+		return nil, ErrEmptySet
+	}
+	n = clamp(n, len(s.ids))
+	if top, ok := s.cache.get(key, n); ok {
+		return append([]string(nil), top...), nil
+	}
+	kh := fnv64aString(key)
+	h := selectTop(make([]entry, 0, n), len(s.ids), n, func(i int) entry {
+		return s.entry(i, kh)
+	})
+	top := make([]string, len(h))
+	for i, e := range h {
+		top[i] = s.ids[e.node]
+	}
+	s.cache.add(key, n, top)
+	return append([]string(nil), top...), nil
+}
+
+// entry returns the entry of the i-th node for a key hashed to kh, in the
+// same order as nodeEntry. The caller must hold s.mu.
+func (s *NodeSet) entry(i int, kh uint64) entry {
+	h := mix64(s.seeds[i], kh)
+	if !s.weighted {
+		return entry{node: i, weight: float64(h >> 11), tie: s.seeds[i]}
+	}
+	return entry{node: i, weight: -s.weights[i] / math.Log(unitFloat(h)), tie: s.seeds[i]}
+}
+
+// changed must be called with s.mu held after the membership changes.
+func (s *NodeSet) changed() {
+	s.weighted = false
+	for _, w := range s.weights {
+		if w != 1 {
+			s.weighted = true
+			break
+		}
+	}
+	s.cache.clear()
+}
+
+// lruCache caches the top nodes of hot keys. A nil *lruCache caches nothing.
+type lruCache struct {
+	mu    sync.Mutex
+	size  int
+	ll    *list.List
+	items map[cacheKey]*list.Element
+}
+
+type cacheKey struct {
+	key string
+	n   int
+}
+
+type cacheItem struct {
+	key cacheKey
+	top []string
+}
+
+func newLRUCache(size int) *lruCache {
+	return &lruCache{size: size, ll: list.New(), items: make(map[cacheKey]*list.Element)}
+}
+
+func (c *lruCache) get(key string, n int) ([]string, bool) {
+	if c == nil {
+		return nil, false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	el, ok := c.items[cacheKey{key, n}]
+	if !ok {
+		return nil, false
+	}
+	c.ll.MoveToFront(el)
+	return el.Value.(*cacheItem).top, true
+}
+
+func (c *lruCache) add(key string, n int, top []string) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	k := cacheKey{key, n}
+	if el, ok := c.items[k]; ok {
+		c.ll.MoveToFront(el)
+		el.Value.(*cacheItem).top = top
+		return
+	}
+	c.items[k] = c.ll.PushFront(&cacheItem{key: k, top: top})
+	if c.ll.Len() > c.size {
+		oldest := c.ll.Back()
+		c.ll.Remove(oldest)
+		delete(c.items, oldest.Value.(*cacheItem).key)
+	}
+}
+
+func (c *lruCache) clear() {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.ll.Init()
+	c.items = make(map[cacheKey]*list.Element)
+}
@@ -0,0 +1,163 @@
+package hrw
+
+import (
+	"reflect"
+	"strconv"
+	"sync"
+	"testing"
+)
+
+func TestNodeSetMatchesTopN(t *testing.T) {
+	var s NodeSet
+	var strs []string
+	var nodes []Node
+	for i := 0; i < 20; i++ {
+		id := "node" + strconv.Itoa(i)
+		s.Add(id)
+		strs = append(strs, id)
+		nodes = append(nodes, Node{ID: id, Weight: 1})
+	}
+	for i := 0; i < 1000; i++ {
+		key := "key" + strconv.Itoa(i)
+		got, err := s.GetN(key, 3)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := TopNStrings(strs, []byte(key), 3); !reflect.DeepEqual(got, want) {
+			t.Fatalf("%s: got %v, expected %v", key, got, want)
+		}
+		one, _ := s.Get(key)
+		if one != got[0] {
+			t.Fatalf("%s: Get returned %s, GetN %v", key, one, got)
+		}
+	}
+
+	s.AddWeight("node3", 5)
+	nodes[3].Weight = 5
+	for i := 0; i < 1000; i++ {
+		key := "key" + strconv.Itoa(i)
+		got, _ := s.GetN(key, 2)
+		want := TopNNodes(nodes, []byte(key), 2)
+		if got[0] != want[0].ID || got[1] != want[1].ID {
+			t.Fatalf("%s: got %v, expected %v", key, got, want)
+		}
+	}
+}
+
+func TestNodeSetRemove(t *testing.T) {
+	s := NewNodeSet(0)
+	for i := 0; i < 10; i++ {
+		s.Add("node" + strconv.Itoa(i))
+	}
+	before := make(map[string]string)
+	for i := 0; i < 1000; i++ {
+		key := "key" + strconv.Itoa(i)
+		before[key], _ = s.Get(key)
+	}
+	s.Remove("node4")
+	if s.Len() != 9 {
+		t.Fatalf("expected 9 nodes, got %d", s.Len())
+	}
+	for key, old := range before {
+		now, _ := s.Get(key)
+		if old != "node4" && now != old {
+			t.Errorf("%s moved from %s to %s", key, old, now)
+		}
+		if now == "node4" {
+			t.Errorf("%s still mapped to removed node", key)
+		}
+	}
+}
+
+func TestNodeSetEmpty(t *testing.T) {
+	s := NewNodeSet(10)
+	if _, err := s.Get("key"); err != ErrEmptySet {
+		t.Errorf("expected ErrEmptySet, got %v", err)
+	}
+	if _, err := s.GetN("key", 2); err != ErrEmptySet {
+		t.Errorf("expected ErrEmptySet, got %v", err)
+	}
+	s.Add("a")
+	s.Remove("a")
+	if _, err := s.Get("key"); err != ErrEmptySet {
+		t.Errorf("expected ErrEmptySet, got %v", err)
+	}
+}
+
+func TestNodeSetAddExisting(t *testing.T) {
+	s := NewNodeSet(2)
+	s.AddWeight("a", 5)
+	s.Add("b")
+	s.Get("key")
+	s.Add("a")
+	if w := s.weights[s.index["a"]]; w != 5 {
+		t.Errorf("Add changed the weight of an existing node to %v", w)
+	}
+	if l := s.cache.ll.Len(); l != 1 {
+		t.Errorf("Add of an existing node cleared the cache")
+	}
+	if s.Len() != 2 {
+		t.Errorf("expected 2 nodes, got %d", s.Len())
+	}
+}
+
+func TestNodeSetCache(t *testing.T) {
+	s := NewNodeSet(2)
+	s.Add("a")
+	if got, _ := s.Get("key"); got != "a" {
+		t.Fatalf("unexpected node %s", got)
+	}
+	// the cached result must not survive a membership change
+	s.Add("b")
+	s.Remove("a")
+	if got, _ := s.Get("key"); got != "b" {
+		t.Fatalf("stale cache entry %s", got)
+	}
+	// callers must not be able to corrupt the cache
+	top, _ := s.GetN("key", 1)
+	top[0] = "x"
+	if got, _ := s.GetN("key", 1); got[0] != "b" {
+		t.Fatalf("cache was modified: %v", got)
+	}
+	for i := 0; i < 10; i++ {
+		s.Get("key" + strconv.Itoa(i))
+	}
+	if l := s.cache.ll.Len(); l != 2 {
+		t.Errorf("expected 2 cached entries, got %d", l)
+	}
+}
+
+func TestNodeSetConcurrent(t *testing.T) {
+	s := NewNodeSet(16)
+	var wg sync.WaitGroup
+	for g := 0; g < 4; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; i < 200; i++ {
+				id := "node" + strconv.Itoa(g*1000+i)
+				s.Add(id)
+				s.GetN("key"+strconv.Itoa(i%20), 3)
+				if i%3 == 0 {
+					s.Remove(id)
+				}
+			}
+		}(g)
+	}
+	wg.Wait()
+	if n := s.Len(); n != 4*(200-67) {
+		t.Errorf("unexpected node count %d", n)
+	}
+}
+
+func BenchmarkNodeSetGet1000(b *testing.B) {
+	s := NewNodeSet(0)
+	for i := 0; i < 1000; i++ {
+		s.Add("node" + strconv.Itoa(i))
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		s.Get("some-key")
+	}
+}
@@ -0,0 +1,151 @@
+package hrw
+
+// Scratch holds reusable buffers for selecting the top N nodes, so that
+// repeated lookups do not allocate once the buffers have grown. A Scratch
+// must not be used by multiple goroutines at the same time; its zero value
+// is ready to use.
+type Scratch struct {
+	// Mixing is used by TopN to compute the weights of int nodes.
+	Mixing Mixing
+
+	heap    []entry
+	ints    []int
+	strings []string
+}
+
+// TopN is like the package level TopN, but uses the scratch buffers. The
+// returned slice is only valid until the next call on s.
+func (s *Scratch) TopN(nodes []int, key []byte, n int) []int {
+	d := s.Mixing.digest(key)
+	s.heap = selectTop(s.heap[:0], len(nodes), n, func(i int) entry {
+		return s.Mixing.entry(i, nodes[i], d)
+	})
+	s.ints = s.ints[:0]
+	for _, e := range s.heap {
+		s.ints = append(s.ints, nodes[e.node])
+	}
+	return s.ints
+}
+
+// TopNStrings is like the package level TopNStrings, but uses the scratch
+// buffers. The returned slice is only valid until the next call on s.
+func (s *Scratch) TopNStrings(nodes []string, key []byte, n int) []string {
+	kh := fnv64a(key)
+	s.heap = selectTop(s.heap[:0], len(nodes), n, func(i int) entry {
+		return stringEntry(i, nodes[i], kh)
+	})
+	s.strings = s.strings[:0]
+	for _, e := range s.heap {
+		s.strings = append(s.strings, nodes[e.node])
+	}
+	return s.strings
+}
+
+// selectTop appends to h the top n of count entries, as returned by score,
+// in decreasing order of weight. n is clamped to [0, count].
+//
+// It keeps a min-heap of the best n entries seen so far, which costs
+// O(count·log n) instead of sorting all entries.
+func selectTop(h []entry, count, n int, score func(i int) entry) []entry {
+	n = clamp(n, count)
+	if n == 0 {
+		return h
+	}
+	for i := 0; i < count; i++ {
+		e := score(i)
+		if len(h) < n {
+			h = append(h, e)
+			siftUp(h, len(h)-1)
+		} else if better(e, h[0]) {
+			h[0] = e
+			siftDown(h, 0)
+		}
+	}
+	// Heap sort: repeatedly move the worst remaining entry to the end.
+	for end := len(h) - 1; end > 0; end-- {
+		h[0], h[end] = h[end], h[0]
+		siftDown(h[:end], 0)
+	}
+	return h
+}
+
+// clamp limits n to [0, max].
+func clamp(n, max int) int {
+	if n > max {
+		n = max
+	}
+	if n < 0 {
+		n = 0
+	}
+	return n
+}
+
+// better reports whether a ranks before b.
+func better(a, b entry) bool {
+	if a.weight != b.weight {
+		return a.weight > b.weight
+	}
+	if a.tie != b.tie {
+		return a.tie < b.tie
+	}
+	return a.node < b.node
+}
+
+// siftUp and siftDown maintain a min-heap, in which the worst entry is at
+// the root.
+func siftUp(h []entry, i int) {
+	for i > 0 {
+		parent := (i - 1) / 2
+		if !better(h[parent], h[i]) {
+			break
+		}
+		h[parent], h[i] = h[i], h[parent]
+		i = parent
+	}
+}
+
+func siftDown(h []entry, i int) {
+	for {
+		worst := i
+		if l := 2*i + 1; l < len(h) && better(h[worst], h[l]) {
+			worst = l
+		}
+		if r := 2*i + 2; r < len(h) && better(h[worst], h[r]) {
+			worst = r
+		}
+		if worst == i {
+			return
+		}
+		h[i], h[worst] = h[worst], h[i]
+		i = worst
+	}
+}
+
+// fnv32a, fnv64a and fnv64aString compute FNV-1a hashes like hash/fnv, but
+// without allocating.
+func fnv32a(b []byte) uint32 {
+	h := uint32(2166136261)
+	for _, c := range b {
+		h ^= uint32(c)
+		h *= 16777619
+	}
+	return h
+}
+
+func fnv64a(b []byte) uint64 {
+	h := uint64(14695981039346656037)
+	for _, c := range b {
+		h ^= uint64(c)
+		h *= 1099511628211
+	}
+	return h
+}
+
+func fnv64aString(s string) uint64 {
+	h := uint64(14695981039346656037)
+	for i := 0; i < len(s); i++ {
+		h ^= uint64(s[i])
+		h *= 1099511628211
+	}
+	return h
+}
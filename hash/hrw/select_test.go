@@ -0,0 +1,127 @@
+package hrw
+
+import (
+	"encoding/binary"
+	"reflect"
+	"strconv"
+	"testing"
+)
+
+func TestTopNMatchesSort(t *testing.T) {
+	nodes := make([]int, 50)
+	for i := range nodes {
+		nodes[i] = i * 7
+	}
+	key := make([]byte, 8)
+	var s Scratch
+	for i := 0; i < 1000; i++ {
+		binary.BigEndian.PutUint64(key, uint64(i))
+		for _, n := range []int{1, 3, 50} {
+			expected := SortByWeight(nodes, key)[:n]
+			if actual := TopN(nodes, key, n); !reflect.DeepEqual(actual, expected) {
+				t.Fatalf("Was %#v, but expected %#v", actual, expected)
+			}
+			if actual := s.TopN(nodes, key, n); !reflect.DeepEqual(actual, expected) {
+				t.Fatalf("Was %#v, but expected %#v", actual, expected)
+			}
+		}
+	}
+}
+
+func TestTopNClamp(t *testing.T) {
+	key := []byte("hello, world")
+	nodes := []int{1, 2, 3, 4, 5}
+	if actual := TopN(nodes, key, 10); !reflect.DeepEqual(actual, SortByWeight(nodes, key)) {
+		t.Errorf("Was %#v, but expected all nodes", actual)
+	}
+	if actual := TopN(nodes, key, -1); len(actual) != 0 {
+		t.Errorf("Was %#v, but expected no nodes", actual)
+	}
+	if actual := TopN(nil, key, 3); len(actual) != 0 {
+		t.Errorf("Was %#v, but expected no nodes", actual)
+	}
+}
+
+func TestScratchNoAlloc(t *testing.T) {
+	nodes := make([]int, 100)
+	strs := make([]string, 100)
+	for i := range nodes {
+		nodes[i] = i
+		strs[i] = "node" + strconv.Itoa(i)
+	}
+	key := []byte("hello, world")
+	var s Scratch
+	s.TopN(nodes, key, 3)
+	s.TopNStrings(strs, key, 3)
+	if allocs := testing.AllocsPerRun(100, func() { s.TopN(nodes, key, 3) }); allocs != 0 {
+		t.Errorf("TopN allocated %v times", allocs)
+	}
+	if allocs := testing.AllocsPerRun(100, func() { s.TopNStrings(strs, key, 3) }); allocs != 0 {
+		t.Errorf("TopNStrings allocated %v times", allocs)
+	}
+}
+
+func BenchmarkTopN3of10(b *testing.B) {
+	benchmarkTopN(b, 10, 3)
+}
+
+func BenchmarkTopN3of100(b *testing.B) {
+	benchmarkTopN(b, 100, 3)
+}
+
+func BenchmarkTopN3of1000(b *testing.B) {
+	benchmarkTopN(b, 1000, 3)
+}
+
+func BenchmarkSortTopN3of10(b *testing.B) {
+	benchmarkSortTopN(b, 10, 3)
+}
+
+func BenchmarkSortTopN3of100(b *testing.B) {
+	benchmarkSortTopN(b, 100, 3)
+}
+
+func BenchmarkSortTopN3of1000(b *testing.B) {
+	benchmarkSortTopN(b, 1000, 3)
+}
+
+func BenchmarkScratchTopN3of1000(b *testing.B) {
+	key := []byte("hello, world")
+	servers := benchmarkServers(1000)
+	var s Scratch
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		s.TopN(servers, key, 3)
+	}
+}
+
+func benchmarkServers(n int) []int {
+	servers := make([]int, n)
+	for i := range servers {
+		servers[i] = i
+	}
+	return servers
+}
+
+func benchmarkTopN(b *testing.B, n, top int) {
+	key := []byte("hello, world")
+	servers := benchmarkServers(n)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		TopN(servers, key, top)
+	}
+}
+
+// benchmarkSortTopN measures the previous implementation of TopN, which
+// sorted every node.
+func benchmarkSortTopN(b *testing.B, n, top int) {
+	key := []byte("hello, world")
+	servers := benchmarkServers(n)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_ = SortByWeight(servers, key)[:top]
+	}
+}
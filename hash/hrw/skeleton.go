@@ -0,0 +1,254 @@
+package hrw
+
+import "sync"
+
+// DefaultFanout is the number of children of each virtual node of a
+// Skeleton created with a non-positive fanout.
+const DefaultFanout = 8
+
+// maxAttempts bounds the number of times a lookup in a Skeleton restarts
+// from the root after reaching an empty leaf.
+const maxAttempts = 64
+
+// Skeleton is a set of equally weighted nodes placed with skeleton-based
+// hierarchical rendezvous hashing, for clusters too large for a linear scan
+// per lookup.
+//
+// Nodes occupy the leaves of a virtual tree in which every virtual node has
+// fanout children. A lookup runs rendezvous hashing among the children of the
+// root, then among the children of the winner and so on down to a leaf, which
+// costs O(fanout·log n) instead of O(n). If the leaf holds no node, the
+// lookup restarts with a new hash of the key, so every node receives the same
+// share of keys. After maxAttempts restarts it only descends into subtrees
+// that hold nodes; so in a sparse tree, for example after most nodes were
+// removed, a lookup may cost maxAttempts descents plus that fallback. Since a
+// node keeps its leaf while it is a member, removing a node only moves the
+// keys that were assigned to it, and a node that fills the hole left by
+// another one takes over exactly its keys. Adding nodes only moves keys to
+// the new nodes, except when the tree is full and a level is added on top of
+// it.
+//
+// Placements depend on the order of Add and Remove calls: replicas must apply
+// the same sequence of membership changes to agree. A Skeleton must be
+// created with NewSkeleton, and is safe for concurrent use.
+type Skeleton struct {
+	mu     sync.RWMutex
+	fanout int
+	slots  []string // node in each leaf, "" for a hole
+	index  map[string]int
+	// live[l][i] is the number of nodes below the i-th virtual node on level
+	// l, where level 0 holds the leaves and the root is the only node on the
+	// top level.
+	live [][]int
+	size int
+}
+
+// NewSkeleton returns an empty Skeleton whose virtual nodes have the given
+// number of children, or DefaultFanout if fanout is less than 2.
+func NewSkeleton(fanout int) *Skeleton {
+	if fanout < 2 {
+		fanout = DefaultFanout
+	}
+	return &Skeleton{fanout: fanout, index: make(map[string]int), live: [][]int{{0}}}
+}
+
+// Add adds a node to the first hole of the tree, or to a new leaf if there is
+// none. Adding an existing node does nothing.
+func (s *Skeleton) Add(id string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.index[id]; ok {
+		return
+	}
+	slot := s.hole()
+	if slot == len(s.live[0]) {
+		s.grow()
+	}
+	if slot == len(s.slots) {
+		s.slots = append(s.slots, "")
+	}
+	s.slots[slot] = id
+	s.index[id] = slot
+	s.size++
+	s.update(slot, 1)
+}
+
+// Remove removes a node, leaving a hole in its leaf.
+func (s *Skeleton) Remove(id string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	slot, ok := s.index[id]
+	if !ok {
+		return
+	}
+	delete(s.index, id)
+	s.slots[slot] = ""
+	s.size--
+	s.update(slot, -1)
+}
+
+// Len returns the number of nodes.
+func (s *Skeleton) Len() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.size
+}
+
+// Get returns the node for key.
+func (s *Skeleton) Get(key string) (string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.size == 0 {
+		return "", ErrEmptySet
+	}
+	kh := fnv64aString(key)
+	for attempt := 0; attempt < maxAttempts; attempt++ {
+		if slot := s.descend(kh, attempt); s.live[0][slot] > 0 {
+			return s.slots[slot], nil
+		}
+	}
+	return s.slots[s.fallback(kh)], nil
+}
+
+// GetN returns n distinct nodes for key; the first one is the node returned
+// by Get. If n exceeds the number of nodes, all nodes are returned.
+//
+// Every key ranks all leaves in a fixed order: first the leaves reached by the
+// maxAttempts attempts of Get, in the order of the attempts, then the others
+// in rendezvous order from the root down, which is the order in which Get
+// falls back. GetN returns the first n nodes in that order, so the result for
+// n is a prefix of the result for n+1, and removing a node does not change the
+// relative order of the others.
+func (s *Skeleton) GetN(key string, n int) ([]string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.size == 0 {
+		return nil, ErrEmptySet
+	}
+	n = clamp(n, s.size)
+	res := make([]string, 0, n)
+	seen := make(map[int]bool, n)
+	kh := fnv64aString(key)
+	for attempt := 0; attempt < maxAttempts && len(res) < n; attempt++ {
+		if slot := s.descend(kh, attempt); s.live[0][slot] > 0 && !seen[slot] {
+			seen[slot] = true
+			res = append(res, s.slots[slot])
+		}
+	}
+	if len(res) < n {
+		// if no attempt reached a node, the first collected leaf is the one
+		// returned by fallback
+		h := make([]entry, 0, s.fanout*len(s.live))
+		res = s.collect(res, seen, h, len(s.live)-1, 0, kh, n)
+	}
+	return res, nil
+}
+
+// descend returns the leaf reached from the root by the given attempt for a
+// key hashed to kh.
+func (s *Skeleton) descend(kh uint64, attempt int) int {
+	kh = mix64(kh, uint64(attempt))
+	i := 0
+	for level := len(s.live) - 1; level > 0; level-- {
+		best := s.entry(level-1, i*s.fanout, kh)
+		for c := best.node + 1; c < (i+1)*s.fanout; c++ {
+			if e := s.entry(level-1, c, kh); better(e, best) {
+				best = e
+			}
+		}
+		i = best.node
+	}
+	return i
+}
+
+// fallback returns the leaf for a key whose attempts all reached holes, by
+// only descending into subtrees that hold nodes. It is the first leaf visited
+// by collect.
+func (s *Skeleton) fallback(kh uint64) int {
+	i := 0
+	for level := len(s.live) - 1; level > 0; level-- {
+		best := entry{node: -1}
+		for c := i * s.fanout; c < (i+1)*s.fanout; c++ {
+			if s.live[level-1][c] == 0 {
+				continue
+			}
+			if e := s.entry(level-1, c, kh); best.node < 0 || better(e, best) {
+				best = e
+			}
+		}
+		i = best.node
+	}
+	return i
+}
+
+// collect appends the nodes below the i-th virtual node on level that are
+// not in seen to res, until res holds n nodes. Children are visited in
+// rendezvous order, skipping empty subtrees. h is scratch space for the
+// ranked children of every level.
+func (s *Skeleton) collect(res []string, seen map[int]bool, h []entry, level, i int, kh uint64, n int) []string {
+	if level == 0 {
+		if !seen[i] {
+			seen[i] = true
+			res = append(res, s.slots[i])
+		}
+		return res
+	}
+	ranked := h[len(h):]
+	for c := i * s.fanout; c < (i+1)*s.fanout; c++ {
+		if s.live[level-1][c] > 0 {
+			ranked = append(ranked, s.entry(level-1, c, kh))
+		}
+	}
+	// insertion sort: there are at most fanout children
+	for j := 1; j < len(ranked); j++ {
+		for k := j; k > 0 && better(ranked[k], ranked[k-1]); k-- {
+			ranked[k], ranked[k-1] = ranked[k-1], ranked[k]
+		}
+	}
+	for _, e := range ranked {
+		if len(res) == n {
+			break
+		}
+		res = s.collect(res, seen, h[:len(h)+len(ranked)], level-1, e.node, kh, n)
+	}
+	return res
+}
+
+// entry returns the rendezvous entry of the i-th virtual node on level for a
+// key hashed to kh. Scores only depend on the position in the tree, so a node
+// that fills a hole takes over the keys of the node it replaces.
+func (s *Skeleton) entry(level, i int, kh uint64) entry {
+	// mix64(level, i) would alias positions on different levels
+	seed := mix64(0, uint64(level)<<56|uint64(i))
+	return entry{node: i, weight: float64(mix64(seed, kh) >> 11)}
+}
+
+// hole returns the first leaf without a node; it is past the allocated
+// leaves if there is no hole among them.
+func (s *Skeleton) hole() int {
+	if s.size < len(s.slots) {
+		for i, id := range s.slots {
+			if id == "" {
+				return i
+			}
+		}
+	}
+	return len(s.slots)
+}
+
+// grow adds a level on top of the full tree. The old root becomes the first
+// child of the new one.
+func (s *Skeleton) grow() {
+	for level := range s.live {
+		s.live[level] = append(s.live[level], make([]int, len(s.live[level])*(s.fanout-1))...)
+	}
+	s.live = append(s.live, []int{s.live[len(s.live)-1][0]})
+}
+
+// update adds delta to the node counts on the path from slot to the root.
+func (s *Skeleton) update(slot, delta int) {
+	for level := range s.live {
+		s.live[level][slot] += delta
+		slot /= s.fanout
+	}
+}
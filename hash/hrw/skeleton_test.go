@@ -0,0 +1,210 @@
+package hrw
+
+import (
+	"math"
+	"reflect"
+	"strconv"
+	"testing"
+)
+
+func newTestSkeleton(fanout, n int) *Skeleton {
+	s := NewSkeleton(fanout)
+	for i := 0; i < n; i++ {
+		s.Add("node" + strconv.Itoa(i))
+	}
+	return s
+}
+
+func skeletonPlacement(s *Skeleton, keys int) map[string]string {
+	res := make(map[string]string, keys)
+	for i := 0; i < keys; i++ {
+		key := "key" + strconv.Itoa(i)
+		res[key], _ = s.Get(key)
+	}
+	return res
+}
+
+func TestSkeletonDistribution(t *testing.T) {
+	const nodes, keys = 100, 200000
+	s := newTestSkeleton(4, nodes)
+	counts := make(map[string]int)
+	for _, node := range skeletonPlacement(s, keys) {
+		counts[node]++
+	}
+	if len(counts) != nodes {
+		t.Fatalf("only %d nodes used", len(counts))
+	}
+	mean := float64(keys) / nodes
+	for node, c := range counts {
+		if math.Abs(float64(c)-mean) > mean*0.15 {
+			t.Errorf("%s got %d keys, expected about %.0f", node, c, mean)
+		}
+	}
+}
+
+func TestSkeletonRemove(t *testing.T) {
+	s := newTestSkeleton(3, 50)
+	before := skeletonPlacement(s, 10000)
+	s.Remove("node17")
+	if s.Len() != 49 {
+		t.Fatalf("expected 49 nodes, got %d", s.Len())
+	}
+	for key, now := range skeletonPlacement(s, 10000) {
+		if old := before[key]; old != "node17" && now != old {
+			t.Errorf("%s moved from %s to %s", key, old, now)
+		}
+		if now == "node17" {
+			t.Errorf("%s still mapped to removed node", key)
+		}
+	}
+}
+
+func TestSkeletonAdd(t *testing.T) {
+	// 26 nodes leave a hole in a full tree of fanout 3
+	s := newTestSkeleton(3, 26)
+	before := skeletonPlacement(s, 10000)
+	s.Add("new")
+	moved := 0
+	for key, now := range skeletonPlacement(s, 10000) {
+		if now != before[key] {
+			if now != "new" {
+				t.Errorf("%s moved from %s to %s", key, before[key], now)
+			}
+			moved++
+		}
+	}
+	if moved == 0 {
+		t.Error("no key moved to the new node")
+	}
+
+	// the tree is full, so this one adds a level
+	s.Add("grow")
+	if s.Len() != 28 || len(s.live) != 5 {
+		t.Fatalf("unexpected tree: %d nodes, %d levels", s.Len(), len(s.live))
+	}
+	for key, node := range skeletonPlacement(s, 10000) {
+		if node == "" {
+			t.Fatalf("%s mapped to a hole", key)
+		}
+	}
+}
+
+func TestSkeletonReplace(t *testing.T) {
+	s := newTestSkeleton(4, 10)
+	before := skeletonPlacement(s, 10000)
+	s.Remove("node3")
+	s.Add("other")
+	if s.Len() != 10 || len(s.slots) != 10 || s.slots[3] != "other" {
+		t.Fatalf("hole not reused: %v", s.slots)
+	}
+	for key, now := range skeletonPlacement(s, 10000) {
+		old := before[key]
+		if old == "node3" && now != "other" || old != "node3" && now != old {
+			t.Errorf("%s moved from %s to %s", key, old, now)
+		}
+	}
+}
+
+func TestSkeletonGetN(t *testing.T) {
+	s := newTestSkeleton(4, 30)
+	for i := 0; i < 1000; i++ {
+		key := "key" + strconv.Itoa(i)
+		top, err := s.GetN(key, 5)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(top) != 5 {
+			t.Fatalf("expected 5 nodes, got %v", top)
+		}
+		if one, _ := s.Get(key); top[0] != one {
+			t.Fatalf("%s: Get returned %s, GetN %v", key, one, top)
+		}
+		seen := make(map[string]bool)
+		for _, node := range top {
+			if seen[node] {
+				t.Fatalf("duplicate node in %v", top)
+			}
+			seen[node] = true
+		}
+	}
+	if all, _ := s.GetN("key", 100); len(all) != 30 {
+		t.Errorf("expected all 30 nodes, got %d", len(all))
+	}
+	if _, err := NewSkeleton(0).GetN("key", 1); err != ErrEmptySet {
+		t.Errorf("expected ErrEmptySet, got %v", err)
+	}
+}
+
+// 稀疏的树中，查找经常回退到fallback，GetN的第一个节点仍要与Get一致
+func TestSkeletonGetNSparse(t *testing.T) {
+	s := newTestSkeleton(DefaultFanout, 5000)
+	for i := 10; i < 5000; i++ {
+		s.Remove("node" + strconv.Itoa(i))
+	}
+	for i := 0; i < 5000; i++ {
+		key := "key" + strconv.Itoa(i)
+		top, _ := s.GetN(key, 3)
+		if one, _ := s.Get(key); len(top) != 3 || top[0] != one {
+			t.Fatalf("%s: Get returned %s, GetN %v", key, one, top)
+		}
+	}
+}
+
+// 删除一个节点后，其余节点在GetN结果中的相对顺序不变，并且结果与n无关
+func TestSkeletonGetNOrder(t *testing.T) {
+	full := newTestSkeleton(4, 500)
+	sparse := newTestSkeleton(4, 200)
+	for i := 10; i < 200; i++ {
+		sparse.Remove("node" + strconv.Itoa(i))
+	}
+	for _, s := range []*Skeleton{full, sparse} {
+		for _, removed := range []int{0, 3, 7} {
+			id := "node" + strconv.Itoa(removed)
+			before := make([][]string, 500)
+			for i := range before {
+				before[i], _ = s.GetN("key"+strconv.Itoa(i), s.Len())
+			}
+			s.Remove(id)
+			for i, all := range before {
+				key := "key" + strconv.Itoa(i)
+				want := make([]string, 0, len(all))
+				for _, node := range all {
+					if node != id {
+						want = append(want, node)
+					}
+				}
+				if got, _ := s.GetN(key, s.Len()); !reflect.DeepEqual(got, want) {
+					t.Fatalf("%s: after removing %s got %v, expected %v", key, id, got, want)
+				}
+				if got, _ := s.GetN(key, 3); !reflect.DeepEqual(got, want[:3]) {
+					t.Fatalf("%s: GetN(3) = %v, expected %v", key, got, want[:3])
+				}
+				if one, _ := s.Get(key); one != want[0] {
+					t.Fatalf("%s: Get returned %s, expected %s", key, one, want[0])
+				}
+			}
+			s.Add(id)
+		}
+	}
+}
+
+func BenchmarkSkeletonGet10000(b *testing.B) {
+	s := newTestSkeleton(DefaultFanout, 10000)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		s.Get("some-key")
+	}
+}
+
+func BenchmarkNodeSetGet10000(b *testing.B) {
+	s := NewNodeSet(0)
+	for i := 0; i < 10000; i++ {
+		s.Add("node" + strconv.Itoa(i))
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		s.Get("some-key")
+	}
+}